@@ -1,7 +1,20 @@
 package config
 
+import (
+	"maps"
+	"slices"
+)
+
 type PkgConfig struct {
+	// Level is the minimum level of the logged messages, parsed with level.Parse. Empty logs every level.
+	Level      string           `mapstructure:"level"`
 	Formatting FormattingConfig `mapstructure:"formatting"`
+	// Loggers configures named loggers by their dot separated name, e.g. payments.stripe.
+	Loggers map[string]LoggerConfig `mapstructure:"loggers"`
+	// Redaction masks sensitive data before entries are encoded.
+	Redaction RedactionConfig `mapstructure:"redaction"`
+	// Enrich adds process and host metadata to every entry.
+	Enrich EnrichConfig `mapstructure:"enrich"`
 }
 
 type FormattingConfig struct {
@@ -13,12 +26,87 @@ type LogConfig struct {
 	FormattingDisabled bool           `mapstructure:"disabled"`
 	Timestamp          string         `mapstructure:"timestamp"`
 	FieldOrder         map[string]int `mapstructure:"field_order"`
+	// Timezone is the zone timestamps are rendered in: Local (default), UTC, a fixed offset such as +02:00,
+	// or a name from the IANA Time Zone database such as Europe/Berlin.
+	Timezone string `mapstructure:"timezone"`
+	// Multiline is how line feeds in the content, metadata values and stack traces are written, see MultilineModes:
+	// indent prefixes continuation lines with MultilinePrefix and drops trailing line feeds, escape writes them as \n
+	// and raw (default) writes them as is. Other control characters are escaped, e.g. \x1b, unless the mode is raw.
+	Multiline string `mapstructure:"multiline"`
+	// MultilinePrefix prefixes continuation lines in the indent mode. Defaults to a tab.
+	MultilinePrefix string `mapstructure:"multiline_prefix"`
 }
 
 type TxConfig struct {
 	FormattingDisabled bool           `mapstructure:"disabled"`
 	Timestamp          string         `mapstructure:"timestamp"`
 	FieldOrder         map[string]int `mapstructure:"field_order"`
+	// Timezone is the zone timestamps are rendered in: Local (default), UTC, a fixed offset such as +02:00,
+	// or a name from the IANA Time Zone database such as Europe/Berlin.
+	Timezone string `mapstructure:"timezone"`
+	// Multiline is how line feeds in the content, metadata values and stack traces are written, see MultilineModes:
+	// indent prefixes continuation lines with MultilinePrefix and drops trailing line feeds, escape writes them as \n
+	// and raw (default) writes them as is. Other control characters are escaped, e.g. \x1b, unless the mode is raw.
+	Multiline string `mapstructure:"multiline"`
+	// MultilinePrefix prefixes continuation lines in the indent mode. Defaults to a tab.
+	MultilinePrefix string `mapstructure:"multiline_prefix"`
+}
+
+// LoggerConfig configures a named logger. Unset values are inherited from the parent logger.
+type LoggerConfig struct {
+	// Level is the minimum level of the logger, parsed with level.Parse.
+	Level string `mapstructure:"level"`
+	// Metadata is added to every message of the logger and its children.
+	Metadata map[string]string `mapstructure:"metadata"`
+	// Driver is where the logger writes: stdout, stderr or file:<path>.
+	Driver string `mapstructure:"driver"`
+}
+
+// RedactionConfig declares the sensitive data removed from entries before they are encoded.
+type RedactionConfig struct {
+	// Keys are metadata keys, or globs such as *_token, whose values are redacted. Matching ignores case.
+	Keys []string `mapstructure:"keys"`
+	// Patterns are names of built-in patterns redacted from the content, metadata values and errors, see RedactionPatterns.
+	Patterns []string `mapstructure:"patterns"`
+	// Expressions are regular expressions redacted like the built-in patterns.
+	// When an expression has a capturing group, only the text matched by the first group is redacted.
+	Expressions []string `mapstructure:"expressions"`
+	// Mode is the replacement of redacted values: mask (default), hash or drop.
+	Mode string `mapstructure:"mode"`
+}
+
+// EnrichConfig switches on the metadata describing the process and host added to every entry.
+type EnrichConfig struct {
+	// Fields lists the enrichers switched on, see EnrichFields.
+	Fields []string `mapstructure:"fields"`
+	// Service is the service name. Defaults to OTEL_SERVICE_NAME, SERVICE_NAME, then the main module of the binary.
+	Service string `mapstructure:"service"`
+	// Environment is the deployment environment, e.g. production. Defaults to ENVIRONMENT.
+	Environment string `mapstructure:"environment"`
 }
 
-var PkgConfiguration PkgConfig
+// PkgConfiguration is the configuration of every Output created without an explicit configuration.
+// Libraries should prefer passing a configuration obtained from one of the Load functions to log.New.
+var PkgConfiguration = Default()
+
+// Clone returns a copy of the configuration that shares no maps with the original.
+func (c PkgConfig) Clone() PkgConfig {
+	c.Formatting.LogConfig.FieldOrder = maps.Clone(c.Formatting.LogConfig.FieldOrder)
+	c.Formatting.TxConfig.FieldOrder = maps.Clone(c.Formatting.TxConfig.FieldOrder)
+
+	if c.Loggers != nil {
+		loggers := make(map[string]LoggerConfig, len(c.Loggers))
+		for name, logger := range c.Loggers {
+			logger.Metadata = maps.Clone(logger.Metadata)
+			loggers[name] = logger
+		}
+		c.Loggers = loggers
+	}
+
+	c.Redaction.Keys = slices.Clone(c.Redaction.Keys)
+	c.Redaction.Patterns = slices.Clone(c.Redaction.Patterns)
+	c.Redaction.Expressions = slices.Clone(c.Redaction.Expressions)
+	c.Enrich.Fields = slices.Clone(c.Enrich.Fields)
+
+	return c
+}
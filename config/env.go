@@ -0,0 +1,260 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EnvPrefix is the prefix of every environment variable read by ApplyEnv.
+const EnvPrefix = "TELEMETRY"
+
+// LoadEnv reads the configuration from the environment on top of the default configuration.
+func LoadEnv() (PkgConfig, error) {
+	return ApplyEnv(Default(), os.Environ())
+}
+
+// LoadFileEnv reads the configuration file on top of the default configuration and overrides it with environ,
+// which has the form returned by os.Environ. The environment takes precedence over the file, and the file over the defaults.
+func LoadFileEnv(name string, environ []string) (PkgConfig, error) {
+	cfg, err := LoadFile(name)
+	if err != nil {
+		return PkgConfig{}, err
+	}
+
+	return ApplyEnv(cfg, environ)
+}
+
+// ApplyEnv returns a copy of cfg where every key set in environ is overridden.
+// environ has the form returned by os.Environ, which makes it possible to override a file configuration
+// with the environment while keeping tests independent of the real environment:
+//
+//	cfg, err := config.LoadFile("telemetry.json")
+//	cfg, err = config.ApplyEnv(cfg, os.Environ())
+//
+// which is what LoadFileEnv does.
+//
+// Every key is read from a variable named after its path in upper case, with dots replaced by underscores
+// and prefixed with EnvPrefix, e.g. formatting.log.timestamp is read from TELEMETRY_FORMATTING_LOG_TIMESTAMP.
+// Map entries append the entry key to the map path, e.g. TELEMETRY_FORMATTING_LOG_FIELD_ORDER_LEVEL,
+// with dots in the entry key written as double underscores, e.g. TELEMETRY_LOGGERS_PAYMENTS__STRIPE_LEVEL.
+// Unknown variables are ignored.
+func ApplyEnv(cfg PkgConfig, environ []string) (PkgConfig, error) {
+	vars := make(map[string]string, len(environ))
+	for _, kv := range environ {
+		name, value, ok := strings.Cut(kv, "=")
+		if ok && strings.HasPrefix(name, EnvPrefix+"_") {
+			vars[name] = value
+		}
+	}
+
+	applied := cfg.Clone()
+	errs := applyEnv(EnvPrefix, reflect.ValueOf(&applied).Elem(), vars)
+	if len(errs) > 0 {
+		return PkgConfig{}, errs
+	}
+
+	err := Validate(applied)
+	if err != nil {
+		return PkgConfig{}, err
+	}
+
+	return applied, nil
+}
+
+func applyEnv(prefix string, v reflect.Value, vars map[string]string) ValidationErrors {
+	var errs ValidationErrors
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		name := prefix + "_" + strings.ToUpper(field.Tag.Get("mapstructure"))
+
+		switch field.Type.Kind() {
+		case reflect.Struct:
+			errs = append(errs, applyEnv(name, v.Field(i), vars)...)
+		case reflect.Map:
+			errs = append(errs, applyEnvMap(name, v.Field(i), vars)...)
+		default:
+			value, ok := vars[name]
+			if !ok {
+				continue
+			}
+
+			err := setValue(v.Field(i), value)
+			if err != nil {
+				errs = append(errs, &ValidationError{Key: name, Msg: err.Error()})
+			}
+		}
+	}
+
+	return errs
+}
+
+func applyEnvMap(prefix string, m reflect.Value, vars map[string]string) ValidationErrors {
+	if m.Type().Elem().Kind() == reflect.Struct {
+		return applyEnvStructMap(prefix, m, vars)
+	}
+
+	var names []string
+	for name := range vars {
+		if strings.HasPrefix(name, prefix+"_") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var errs ValidationErrors
+	for _, name := range names {
+		key := strings.ToLower(strings.TrimPrefix(name, prefix+"_"))
+		elem := reflect.New(m.Type().Elem()).Elem()
+		err := setValue(elem, vars[name])
+		if err != nil {
+			errs = append(errs, &ValidationError{Key: name, Msg: err.Error()})
+			continue
+		}
+
+		if m.IsNil() {
+			m.Set(reflect.MakeMap(m.Type()))
+		}
+		m.SetMapIndex(reflect.ValueOf(key), elem)
+	}
+
+	return errs
+}
+
+// applyEnvStructMap overrides the entries of a map of structs, read from variables named <PREFIX>_<ENTRY>_<FIELD>,
+// e.g. TELEMETRY_LOGGERS_PAYMENTS__STRIPE_LEVEL, where dots in the entry name are written as double underscores
+// and single underscores are kept, e.g. TELEMETRY_LOGGERS_PAYMENT_API_LEVEL names the entry payment_api.
+// Map fields of the entries are matched first, see envEntry.
+func applyEnvStructMap(prefix string, m reflect.Value, vars map[string]string) ValidationErrors {
+	elemType := m.Type().Elem()
+
+	entries := make(map[string]bool)
+	for name := range vars {
+		if !strings.HasPrefix(name, prefix+"_") {
+			continue
+		}
+
+		if entry, ok := envEntry(strings.TrimPrefix(name, prefix+"_"), elemType); ok {
+			entries[entry] = true
+		}
+	}
+
+	names := make([]string, 0, len(entries))
+	for entry := range entries {
+		names = append(names, entry)
+	}
+	sort.Strings(names)
+
+	var errs ValidationErrors
+	for _, entry := range names {
+		key := reflect.ValueOf(strings.ToLower(strings.ReplaceAll(entry, "__", ".")))
+
+		elem := reflect.New(elemType).Elem()
+		if existing := m.MapIndex(key); existing.IsValid() {
+			elem.Set(existing)
+		}
+
+		errs = append(errs, applyEnv(prefix+"_"+entry, elem, vars)...)
+
+		if m.IsNil() {
+			m.Set(reflect.MakeMap(m.Type()))
+		}
+		m.SetMapIndex(key, elem)
+	}
+
+	return errs
+}
+
+// envEntry returns the entry named by the end of a variable name, e.g. PAYMENTS for PAYMENTS_LEVEL.
+// Map fields are matched before the other fields, so that PAYMENTS_METADATA_LOG_LEVEL is read as the log_level
+// metadata of the entry PAYMENTS rather than the level of the entry PAYMENTS_METADATA_LOG.
+func envEntry(rest string, t reflect.Type) (string, bool) {
+	for _, maps := range []bool{true, false} {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if (field.Type.Kind() == reflect.Map) != maps {
+				continue
+			}
+
+			tag := "_" + strings.ToUpper(field.Tag.Get("mapstructure"))
+			end := -1
+			if maps {
+				end = strings.Index(rest, tag+"_")
+			} else if strings.HasSuffix(rest, tag) {
+				end = len(rest) - len(tag)
+			}
+
+			if end > 0 {
+				return rest[:end], true
+			}
+		}
+	}
+
+	return "", false
+}
+
+func setValue(v reflect.Value, value string) error {
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", value)
+		}
+
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid unsigned integer %q", value)
+		}
+
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+
+		v.SetFloat(f)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %s", v.Type())
+		}
+
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); len(item) > 0 {
+				items = append(items, item)
+			}
+		}
+
+		v.Set(reflect.ValueOf(items).Convert(v.Type()))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+
+	return nil
+}
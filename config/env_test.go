@@ -0,0 +1,99 @@
+package config
+
+import (
+	"errors"
+	"gotest.tools/v3/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestApplyEnv(t *testing.T) {
+	t.Run("EVERY KEY", func(t *testing.T) {
+		environ := []string{
+			"TELEMETRY_FORMATTING_LOG_DISABLED=true",
+			"TELEMETRY_FORMATTING_LOG_TIMESTAMP=15:04",
+			"TELEMETRY_FORMATTING_LOG_FIELD_ORDER_TIMESTAMP=4",
+			"TELEMETRY_FORMATTING_LOG_FIELD_ORDER_BUFFER=1",
+			"TELEMETRY_FORMATTING_TRANSACTION_DISABLED=1",
+			"TELEMETRY_FORMATTING_TRANSACTION_TIMESTAMP=2006",
+			"TELEMETRY_FORMATTING_TRANSACTION_FIELD_ORDER_LEVEL=3",
+			"TELEMETRY_FORMATTING_TRANSACTION_FIELD_ORDER_METADATA=2",
+		}
+
+		cfg, err := ApplyEnv(Default(), environ)
+		assert.NilError(t, err)
+		assert.Equal(t, cfg.Formatting.LogConfig.FormattingDisabled, true)
+		assert.Equal(t, cfg.Formatting.LogConfig.Timestamp, "15:04")
+		assert.DeepEqual(t, cfg.Formatting.LogConfig.FieldOrder, map[string]int{"timestamp": 4, "level": 2, "metadata": 3, "buffer": 1})
+		assert.Equal(t, cfg.Formatting.TxConfig.FormattingDisabled, true)
+		assert.Equal(t, cfg.Formatting.TxConfig.Timestamp, "2006")
+		assert.DeepEqual(t, cfg.Formatting.TxConfig.FieldOrder, map[string]int{"timestamp": 1, "level": 3, "metadata": 2, "buffer": 4})
+	})
+
+	t.Run("DOES NOT MODIFY THE GIVEN CONFIG", func(t *testing.T) {
+		cfg := Default()
+		_, err := ApplyEnv(cfg, []string{"TELEMETRY_FORMATTING_LOG_FIELD_ORDER_TIMESTAMP=5"})
+		assert.NilError(t, err)
+		assert.DeepEqual(t, cfg, Default())
+	})
+
+	t.Run("IGNORES OTHER VARIABLES", func(t *testing.T) {
+		cfg, err := ApplyEnv(Default(), []string{"HOME=/root", "TELEMETRY_ENDPOINT=localhost", "TELEMETRY"})
+		assert.NilError(t, err)
+		assert.DeepEqual(t, cfg, Default())
+	})
+
+	t.Run("INVALID VALUES", func(t *testing.T) {
+		environ := []string{
+			"TELEMETRY_FORMATTING_LOG_DISABLED=maybe",
+			"TELEMETRY_FORMATTING_LOG_FIELD_ORDER_LEVEL=second",
+		}
+
+		var errs ValidationErrors
+		_, err := ApplyEnv(Default(), environ)
+		assert.Assert(t, errors.As(err, &errs))
+		assert.Equal(t, len(errs), 2)
+		assert.Equal(t, errs[0].Error(), `TELEMETRY_FORMATTING_LOG_DISABLED: invalid boolean "maybe"`)
+		assert.Equal(t, errs[1].Error(), `TELEMETRY_FORMATTING_LOG_FIELD_ORDER_LEVEL: invalid integer "second"`)
+
+		_, err = ApplyEnv(Default(), []string{"TELEMETRY_FORMATTING_LOG_FIELD_ORDER_LEVEL=1"})
+		assert.ErrorContains(t, err, `duplicate position 1`)
+	})
+
+	t.Run("PRECEDENCE", func(t *testing.T) {
+		name := filepath.Join(t.TempDir(), "telemetry.json")
+		content := `{"formatting": {"log": {"timestamp": "2006", "disabled": true}}}`
+		assert.NilError(t, os.WriteFile(name, []byte(content), 0600))
+
+		cfg, err := LoadFileEnv(name, []string{"TELEMETRY_FORMATTING_LOG_TIMESTAMP=15:04"})
+		assert.NilError(t, err)
+
+		//env over file
+		assert.Equal(t, cfg.Formatting.LogConfig.Timestamp, "15:04")
+		//file over defaults
+		assert.Equal(t, cfg.Formatting.LogConfig.FormattingDisabled, true)
+		//defaults
+		assert.Equal(t, cfg.Formatting.TxConfig.Timestamp, Default().Formatting.TxConfig.Timestamp)
+	})
+}
+
+func TestApplyEnvLoggers(t *testing.T) {
+	environ := []string{
+		"TELEMETRY_LOGGERS_PAYMENTS__STRIPE_LEVEL=debug",
+		"TELEMETRY_LOGGERS_PAYMENTS__STRIPE_METADATA_TEAM=payments",
+		"TELEMETRY_LOGGERS_PAYMENTS_DRIVER=stderr",
+		//metadata keys ending like a field of the logger are still metadata keys
+		"TELEMETRY_LOGGERS_PAYMENTS_METADATA_LOG_LEVEL=debug",
+		//single underscores are part of the logger name
+		"TELEMETRY_LOGGERS_PAYMENT_API_LEVEL=warn",
+	}
+
+	cfg, err := ApplyEnv(Default(), environ)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, cfg.Loggers, map[string]LoggerConfig{
+		"payments.stripe": {Level: "debug", Metadata: map[string]string{"team": "payments"}},
+		"payments":        {Driver: "stderr", Metadata: map[string]string{"log_level": "debug"}},
+		"payment_api":     {Level: "warn"},
+	})
+}
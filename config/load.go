@@ -0,0 +1,139 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/spf13/viper"
+	"io"
+	"reflect"
+)
+
+// Default returns the configuration used when nothing else is configured.
+func Default() PkgConfig {
+	return PkgConfig{
+		Formatting: FormattingConfig{
+			LogConfig: LogConfig{
+				Timestamp:  "2006-01-02 15:04:05",
+				FieldOrder: defaultOrder(),
+			},
+			TxConfig: TxConfig{
+				Timestamp:  "2006-01-02 15:04:05",
+				FieldOrder: defaultOrder(),
+			},
+		},
+	}
+}
+
+func defaultOrder() map[string]int {
+	order := make(map[string]int, len(FieldNames))
+	for i, field := range FieldNames {
+		order[field] = i + 1
+	}
+
+	return order
+}
+
+// Load validates the given configuration and returns a copy of it.
+func Load(cfg PkgConfig) (PkgConfig, error) {
+	err := Validate(cfg)
+	if err != nil {
+		return PkgConfig{}, err
+	}
+
+	return cfg.Clone(), nil
+}
+
+// LoadFile reads the configuration file on top of the default configuration.
+// A field_order set by the file replaces the default order as a whole.
+// The format is deduced from the file extension.
+func LoadFile(name string) (PkgConfig, error) {
+	cfg := Default()
+	err := DecodeFile(name, &cfg)
+	if err != nil {
+		return PkgConfig{}, err
+	}
+
+	return cfg, nil
+}
+
+// LoadBytes reads the configuration from data on top of the default configuration.
+// The format is any format supported by viper, e.g. json, yaml or toml.
+func LoadBytes(data []byte, format string) (PkgConfig, error) {
+	return LoadReader(bytes.NewReader(data), format)
+}
+
+// LoadReader reads the configuration from r on top of the default configuration.
+// The format is any format supported by viper, e.g. json, yaml or toml.
+func LoadReader(r io.Reader, format string) (PkgConfig, error) {
+	v := newViper()
+	v.SetConfigType(format)
+	err := v.ReadConfig(r)
+	if err != nil {
+		return PkgConfig{}, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	cfg := Default()
+	err = decode(v, &cfg)
+	if err != nil {
+		return PkgConfig{}, err
+	}
+
+	return cfg, nil
+}
+
+// DecodeFile reads the configuration file and decodes it on top of cfg.
+// Unknown keys and invalid values are reported as ValidationErrors, in which case cfg is left untouched.
+func DecodeFile(name string, cfg *PkgConfig) error {
+	v := newViper()
+	v.SetConfigFile(name)
+	err := v.ReadInConfig()
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+
+	return withFile(decode(v, cfg), name)
+}
+
+// ValidateFile reports every unknown key and invalid value of the configuration file.
+func ValidateFile(name string) error {
+	var cfg PkgConfig
+	return DecodeFile(name, &cfg)
+}
+
+// newViper returns a viper instance that does not treat dots as key delimiters,
+// since they separate the names of nested loggers.
+func newViper() *viper.Viper {
+	return viper.NewWithOptions(viper.KeyDelimiter("::"))
+}
+
+// decode validates the settings read by v and decodes them on top of cfg.
+// cfg is only modified when the settings are valid.
+func decode(v *viper.Viper, cfg *PkgConfig) error {
+	errs := unknownKeys("", v.AllSettings(), reflect.TypeOf(PkgConfig{}))
+	if len(errs) > 0 {
+		return errs
+	}
+
+	decoded := cfg.Clone()
+	//a field order read from the settings replaces the one of cfg instead of being merged into it,
+	//a partial order would otherwise collide with the default positions
+	if v.IsSet("formatting::log::field_order") {
+		decoded.Formatting.LogConfig.FieldOrder = nil
+	}
+	if v.IsSet("formatting::transaction::field_order") {
+		decoded.Formatting.TxConfig.FieldOrder = nil
+	}
+
+	err := v.Unmarshal(&decoded)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+
+	err = Validate(decoded)
+	if err != nil {
+		return err
+	}
+
+	*cfg = decoded
+	return nil
+}
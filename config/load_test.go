@@ -0,0 +1,75 @@
+package config
+
+import (
+	"gotest.tools/v3/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	t.Run("DEFAULT", func(t *testing.T) {
+		assert.NilError(t, Validate(Default()))
+		assert.DeepEqual(t, Default().Formatting.LogConfig.FieldOrder, map[string]int{"timestamp": 1, "level": 2, "metadata": 3, "buffer": 4})
+	})
+
+	t.Run("STRUCT", func(t *testing.T) {
+		cfg := Default()
+		loaded, err := Load(cfg)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, loaded, cfg)
+
+		//the loaded configuration must not share maps with the given one
+		loaded.Formatting.LogConfig.FieldOrder["buffer"] = 10
+		assert.Equal(t, cfg.Formatting.LogConfig.FieldOrder["buffer"], 4)
+
+		cfg.Formatting.LogConfig.Timestamp = "now"
+		_, err = Load(cfg)
+		assert.ErrorContains(t, err, "formatting.log.timestamp")
+	})
+
+	t.Run("BYTES", func(t *testing.T) {
+		cfg, err := LoadBytes([]byte(`{"formatting": {"log": {"disabled": true}}}`), "json")
+		assert.NilError(t, err)
+		assert.Equal(t, cfg.Formatting.LogConfig.FormattingDisabled, true)
+		assert.Equal(t, cfg.Formatting.LogConfig.Timestamp, Default().Formatting.LogConfig.Timestamp)
+
+		_, err = LoadBytes([]byte(`{"formatting": {"lgo": {"disabled": true}}}`), "json")
+		assert.ErrorContains(t, err, "formatting.lgo: unknown key")
+	})
+
+	t.Run("PARTIAL FIELD ORDER", func(t *testing.T) {
+		cfg, err := LoadBytes([]byte(`{"formatting": {"log": {"field_order": {"buffer": 1}}}}`), "json")
+		assert.NilError(t, err)
+		//the order of the file replaces the default order instead of being merged into it
+		assert.DeepEqual(t, cfg.Formatting.LogConfig.FieldOrder, map[string]int{"buffer": 1})
+		assert.DeepEqual(t, cfg.Formatting.TxConfig.FieldOrder, Default().Formatting.TxConfig.FieldOrder)
+
+		name := filepath.Join(t.TempDir(), "telemetry.json")
+		assert.NilError(t, os.WriteFile(name, []byte(`{"formatting": {"transaction": {"field_order": {"level": 1, "timestamp": 2}}}}`), 0600))
+		cfg, err = LoadFile(name)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, cfg.Formatting.TxConfig.FieldOrder, map[string]int{"level": 1, "timestamp": 2})
+	})
+
+	t.Run("READER", func(t *testing.T) {
+		yaml := "formatting:\n  transaction:\n    timestamp: \"15:04\"\n"
+		cfg, err := LoadReader(strings.NewReader(yaml), "yaml")
+		assert.NilError(t, err)
+		assert.Equal(t, cfg.Formatting.TxConfig.Timestamp, "15:04")
+	})
+
+	t.Run("FILE", func(t *testing.T) {
+		name := filepath.Join(t.TempDir(), "telemetry.json")
+		assert.NilError(t, os.WriteFile(name, []byte(`{"formatting": {"log": {"timestamp": "2006"}}}`), 0600))
+
+		cfg, err := LoadFile(name)
+		assert.NilError(t, err)
+		assert.Equal(t, cfg.Formatting.LogConfig.Timestamp, "2006")
+		assert.Equal(t, cfg.Formatting.TxConfig.Timestamp, Default().Formatting.TxConfig.Timestamp)
+
+		_, err = LoadFile(filepath.Join(t.TempDir(), "missing.json"))
+		assert.ErrorContains(t, err, "failed to read config")
+	})
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// locations caches the locations returned by Location, since loading a zone reads the time zone database.
+var locations sync.Map
+
+// Location returns the location described by a Timezone setting: Local or empty for the local zone, UTC,
+// a fixed offset such as +02:00 or -0530, or a name from the IANA Time Zone database such as Europe/Berlin.
+func Location(name string) (*time.Location, error) {
+	switch name {
+	case "", "Local", "local":
+		return time.Local, nil
+	case "UTC", "utc", "Z":
+		return time.UTC, nil
+	}
+
+	if loc, ok := locations.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+
+	var loc *time.Location
+	if strings.HasPrefix(name, "+") || strings.HasPrefix(name, "-") {
+		offset, ok := parseOffset(name)
+		if !ok {
+			return nil, fmt.Errorf("invalid timezone offset %q, expected +hh:mm or -hh:mm", name)
+		}
+
+		loc = time.FixedZone(name, offset)
+	} else {
+		var err error
+		loc, err = time.LoadLocation(name)
+		if err != nil {
+			return nil, fmt.Errorf("unknown timezone %q, expected Local, UTC, an offset such as +02:00 or a name such as Europe/Berlin", name)
+		}
+	}
+
+	locations.Store(name, loc)
+	return loc, nil
+}
+
+// parseOffset returns the offset in seconds east of UTC of +hh, +hhmm or +hh:mm.
+func parseOffset(s string) (int, bool) {
+	sign := 1
+	if s[0] == '-' {
+		sign = -1
+	}
+
+	digits := strings.ReplaceAll(s[1:], ":", "")
+	if len(digits) != 2 && len(digits) != 4 {
+		return 0, false
+	}
+
+	hours, err := strconv.Atoi(digits[:2])
+	if err != nil || hours > 14 {
+		return 0, false
+	}
+
+	var minutes int
+	if len(digits) == 4 {
+		minutes, err = strconv.Atoi(digits[2:])
+		if err != nil || minutes > 59 {
+			return 0, false
+		}
+	}
+
+	return sign * (hours*3600 + minutes*60), true
+}
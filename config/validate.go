@@ -0,0 +1,338 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"github.com/canghel3/telemetry/level"
+	"path"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FieldNames lists the fields that can be ordered using field_order.
+var FieldNames = []string{"timestamp", "level", "metadata", "buffer"}
+
+// RedactionPatterns lists the built-in patterns that can be redacted using redaction.patterns.
+var RedactionPatterns = []string{"credit_card", "bearer_token", "email"}
+
+// EnrichFields lists the enrichers that can be switched on using enrich.fields.
+var EnrichFields = []string{"hostname", "pid", "service", "version", "environment", "kubernetes"}
+
+// RedactionModes lists the replacements of redacted values.
+var RedactionModes = []string{"mask", "hash", "drop"}
+
+// MultilineModes lists the ways line feeds can be written using multiline.
+var MultilineModes = []string{"indent", "escape", "raw"}
+
+// ValidationError describes a single problem found in a configuration.
+type ValidationError struct {
+	// File is the configuration file the problem was found in. Empty when validating a PkgConfig directly.
+	File string
+	// Key is the dot separated path of the offending key, e.g. formatting.log.field_order.
+	Key string
+	Msg string
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.File) > 0 {
+		return fmt.Sprintf("%s: %s: %s", e.File, e.Key, e.Msg)
+	}
+
+	return fmt.Sprintf("%s: %s", e.Key, e.Msg)
+}
+
+// ValidationErrors holds every problem found in a configuration.
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+
+	return strings.Join(msgs, "\n")
+}
+
+// Validate reports every invalid value of the configuration.
+// The returned error is either nil or of type ValidationErrors.
+func Validate(cfg PkgConfig) error {
+	var errs ValidationErrors
+	if len(cfg.Level) > 0 {
+		_, err := level.Parse(cfg.Level)
+		if err != nil {
+			errs = append(errs, &ValidationError{Key: "level", Msg: err.Error()})
+		}
+	}
+
+	errs = append(errs, validateFormatting("formatting.log", cfg.Formatting.LogConfig)...)
+	errs = append(errs, validateFormatting("formatting.transaction", LogConfig(cfg.Formatting.TxConfig))...)
+	errs = append(errs, validateLoggers(cfg.Loggers)...)
+	errs = append(errs, validateRedaction(cfg.Redaction)...)
+
+	for i, field := range cfg.Enrich.Fields {
+		if !slices.Contains(EnrichFields, field) {
+			errs = append(errs, &ValidationError{
+				Key: fmt.Sprintf("enrich.fields.%d", i),
+				Msg: fmt.Sprintf("unknown field %q, expected one of %s", field, strings.Join(EnrichFields, ", ")),
+			})
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func validateFormatting(key string, cfg LogConfig) ValidationErrors {
+	var errs ValidationErrors
+
+	timestamp, fieldOrder := cfg.Timestamp, cfg.FieldOrder
+	if len(timestamp) > 0 && !validLayout(timestamp) {
+		errs = append(errs, &ValidationError{
+			Key: key + ".timestamp",
+			Msg: fmt.Sprintf("invalid timestamp layout %q, layouts are written using the reference time 2006-01-02 15:04:05", timestamp),
+		})
+	}
+
+	if _, err := Location(cfg.Timezone); err != nil {
+		errs = append(errs, &ValidationError{Key: key + ".timezone", Msg: err.Error()})
+	}
+
+	if len(cfg.Multiline) > 0 && !slices.Contains(MultilineModes, cfg.Multiline) {
+		errs = append(errs, &ValidationError{
+			Key: key + ".multiline",
+			Msg: fmt.Sprintf("unknown mode %q, expected one of %s", cfg.Multiline, strings.Join(MultilineModes, ", ")),
+		})
+	}
+
+	if strings.ContainsAny(cfg.MultilinePrefix, "\r\n") {
+		errs = append(errs, &ValidationError{
+			Key: key + ".multiline_prefix",
+			Msg: fmt.Sprintf("invalid prefix %q, prefixes cannot hold line breaks", cfg.MultilinePrefix),
+		})
+	}
+
+	fields := make([]string, 0, len(fieldOrder))
+	for field := range fieldOrder {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	positions := make(map[int]string)
+	for _, field := range fields {
+		position := fieldOrder[field]
+		if !knownField(field) {
+			errs = append(errs, &ValidationError{
+				Key: key + ".field_order." + field,
+				Msg: fmt.Sprintf("unknown field %q, expected one of %s", field, strings.Join(FieldNames, ", ")),
+			})
+		}
+
+		if position < 1 {
+			errs = append(errs, &ValidationError{
+				Key: key + ".field_order." + field,
+				Msg: fmt.Sprintf("invalid position %d, positions start at 1", position),
+			})
+			continue
+		}
+
+		if other, ok := positions[position]; ok {
+			errs = append(errs, &ValidationError{
+				Key: key + ".field_order." + field,
+				Msg: fmt.Sprintf("duplicate position %d, already used by %q", position, other),
+			})
+			continue
+		}
+
+		positions[position] = field
+	}
+
+	return errs
+}
+
+func validateLoggers(loggers map[string]LoggerConfig) ValidationErrors {
+	names := make([]string, 0, len(loggers))
+	for name := range loggers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs ValidationErrors
+	for _, name := range names {
+		logger := loggers[name]
+		key := "loggers." + name
+
+		if len(name) == 0 || strings.HasPrefix(name, ".") || strings.HasSuffix(name, ".") || strings.Contains(name, "..") {
+			errs = append(errs, &ValidationError{Key: key, Msg: fmt.Sprintf("invalid logger name %q", name)})
+		}
+
+		if len(logger.Level) > 0 {
+			_, err := level.Parse(logger.Level)
+			if err != nil {
+				errs = append(errs, &ValidationError{Key: key + ".level", Msg: err.Error()})
+			}
+		}
+
+		if len(logger.Driver) > 0 && !validDriver(logger.Driver) {
+			errs = append(errs, &ValidationError{
+				Key: key + ".driver",
+				Msg: fmt.Sprintf("invalid driver %q, expected stdout, stderr or file:<path>", logger.Driver),
+			})
+		}
+	}
+
+	return errs
+}
+
+func validateRedaction(cfg RedactionConfig) ValidationErrors {
+	var errs ValidationErrors
+
+	for i, key := range cfg.Keys {
+		_, err := path.Match(key, "")
+		if len(key) == 0 || err != nil {
+			errs = append(errs, &ValidationError{
+				Key: fmt.Sprintf("redaction.keys.%d", i),
+				Msg: fmt.Sprintf("invalid key %q", key),
+			})
+		}
+	}
+
+	for i, pattern := range cfg.Patterns {
+		if !slices.Contains(RedactionPatterns, pattern) {
+			errs = append(errs, &ValidationError{
+				Key: fmt.Sprintf("redaction.patterns.%d", i),
+				Msg: fmt.Sprintf("unknown pattern %q, expected one of %s", pattern, strings.Join(RedactionPatterns, ", ")),
+			})
+		}
+	}
+
+	for i, expression := range cfg.Expressions {
+		_, err := regexp.Compile(expression)
+		if err != nil {
+			errs = append(errs, &ValidationError{
+				Key: fmt.Sprintf("redaction.expressions.%d", i),
+				Msg: fmt.Sprintf("invalid expression: %s", err.Error()),
+			})
+		}
+	}
+
+	if len(cfg.Mode) > 0 && !slices.Contains(RedactionModes, cfg.Mode) {
+		errs = append(errs, &ValidationError{
+			Key: "redaction.mode",
+			Msg: fmt.Sprintf("unknown mode %q, expected one of %s", cfg.Mode, strings.Join(RedactionModes, ", ")),
+		})
+	}
+
+	return errs
+}
+
+func validDriver(driver string) bool {
+	switch {
+	case driver == "stdout", driver == "stderr":
+		return true
+	case strings.HasPrefix(driver, "file:"):
+		return len(strings.TrimPrefix(driver, "file:")) > 0
+	default:
+		return false
+	}
+}
+
+func knownField(field string) bool {
+	for _, name := range FieldNames {
+		if name == field {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validLayout reports whether the layout contains at least one time element
+// and can parse the timestamps it produces.
+func validLayout(layout string) bool {
+	t1 := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+	t2 := time.Date(2012, 11, 22, 16, 17, 18, 0, time.UTC)
+	if t1.Format(layout) == t2.Format(layout) {
+		return false
+	}
+
+	_, err := time.Parse(layout, t1.Format(layout))
+	return err == nil
+}
+
+// unknownKeys returns the dot separated path of every key in settings that has no matching field in t.
+func unknownKeys(prefix string, settings map[string]any, t reflect.Type) ValidationErrors {
+	var errs ValidationErrors
+
+	keys := make([]string, 0, len(settings))
+	for k := range settings {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		key := k
+		if len(prefix) > 0 {
+			key = prefix + "." + k
+		}
+
+		field, ok := fieldByTag(t, k)
+		if !ok {
+			errs = append(errs, &ValidationError{Key: key, Msg: "unknown key"})
+			continue
+		}
+
+		nested, ok := settings[k].(map[string]any)
+		if !ok {
+			continue
+		}
+
+		switch {
+		case field.Type.Kind() == reflect.Struct:
+			errs = append(errs, unknownKeys(key, nested, field.Type)...)
+		case field.Type.Kind() == reflect.Map && field.Type.Elem().Kind() == reflect.Struct:
+			entries := make([]string, 0, len(nested))
+			for entry := range nested {
+				entries = append(entries, entry)
+			}
+			sort.Strings(entries)
+
+			for _, entry := range entries {
+				if values, ok := nested[entry].(map[string]any); ok {
+					errs = append(errs, unknownKeys(key+"."+entry, values, field.Type.Elem())...)
+				}
+			}
+		}
+	}
+
+	return errs
+}
+
+func fieldByTag(t reflect.Type, key string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if strings.EqualFold(field.Tag.Get("mapstructure"), key) {
+			return field, true
+		}
+	}
+
+	return reflect.StructField{}, false
+}
+
+// withFile sets the file of every validation error contained in err.
+func withFile(err error, file string) error {
+	var errs ValidationErrors
+	if errors.As(err, &errs) {
+		for _, e := range errs {
+			e.File = file
+		}
+	}
+
+	return err
+}
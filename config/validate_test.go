@@ -0,0 +1,182 @@
+package config
+
+import (
+	"errors"
+	"gotest.tools/v3/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	t.Run("VALID", func(t *testing.T) {
+		cfg := PkgConfig{
+			Formatting: FormattingConfig{
+				LogConfig: LogConfig{
+					Timestamp:  "2006-01-02 15:04:05",
+					FieldOrder: map[string]int{"timestamp": 1, "level": 2, "metadata": 3, "buffer": 4},
+				},
+			},
+		}
+
+		assert.NilError(t, Validate(cfg))
+		assert.NilError(t, Validate(PkgConfig{}))
+	})
+
+	t.Run("INVALID TIMESTAMP LAYOUT", func(t *testing.T) {
+		cfg := PkgConfig{Formatting: FormattingConfig{TxConfig: TxConfig{Timestamp: "yyyy-mm-dd"}}}
+
+		var errs ValidationErrors
+		assert.Assert(t, errors.As(Validate(cfg), &errs))
+		assert.Equal(t, len(errs), 1)
+		assert.Equal(t, errs[0].Key, "formatting.transaction.timestamp")
+	})
+
+	t.Run("BAD FIELD NAMES AND DUPLICATE POSITIONS", func(t *testing.T) {
+		cfg := PkgConfig{
+			Formatting: FormattingConfig{
+				LogConfig: LogConfig{
+					FieldOrder: map[string]int{"timestamp": 1, "level": 1, "lvl": 2, "buffer": 0},
+				},
+			},
+		}
+
+		var errs ValidationErrors
+		assert.Assert(t, errors.As(Validate(cfg), &errs))
+		assert.Equal(t, len(errs), 3)
+		assert.Equal(t, errs[0].Key, "formatting.log.field_order.buffer")
+		assert.ErrorContains(t, errs[0], "invalid position 0")
+		assert.Equal(t, errs[1].Key, "formatting.log.field_order.lvl")
+		assert.ErrorContains(t, errs[1], `unknown field "lvl"`)
+		assert.Equal(t, errs[2].Key, "formatting.log.field_order.timestamp")
+		assert.ErrorContains(t, errs[2], `duplicate position 1, already used by "level"`)
+	})
+}
+
+func TestValidateFile(t *testing.T) {
+	t.Run("UNKNOWN KEYS", func(t *testing.T) {
+		name := filepath.Join(t.TempDir(), "telemetry.json")
+		content := `{"formatting": {"log": {"feild_order": {"timestamp": 1}, "timestamp": "2006"}, "colors": true}}`
+		assert.NilError(t, os.WriteFile(name, []byte(content), 0600))
+
+		var errs ValidationErrors
+		assert.Assert(t, errors.As(ValidateFile(name), &errs))
+		assert.Equal(t, len(errs), 2)
+		assert.Equal(t, errs[0].Error(), name+": formatting.colors: unknown key")
+		assert.Equal(t, errs[1].Error(), name+": formatting.log.feild_order: unknown key")
+	})
+
+	t.Run("DECODE KEEPS CONFIG ON ERROR", func(t *testing.T) {
+		name := filepath.Join(t.TempDir(), "telemetry.json")
+		content := `{"formatting": {"log": {"timestamp": "today"}}}`
+		assert.NilError(t, os.WriteFile(name, []byte(content), 0600))
+
+		cfg := PkgConfig{Formatting: FormattingConfig{LogConfig: LogConfig{Timestamp: "2006"}}}
+		err := DecodeFile(name, &cfg)
+		assert.ErrorContains(t, err, name+": formatting.log.timestamp: invalid timestamp layout")
+		assert.Equal(t, cfg.Formatting.LogConfig.Timestamp, "2006")
+	})
+
+	t.Run("REPOSITORY CONFIG", func(t *testing.T) {
+		assert.NilError(t, ValidateFile("../telemetry.json"))
+	})
+}
+
+func TestValidateLevel(t *testing.T) {
+	assert.NilError(t, Validate(PkgConfig{Level: "warning"}))
+	assert.ErrorContains(t, Validate(PkgConfig{Level: "loud"}), `level: unknown level "loud"`)
+}
+
+func TestValidateLoggers(t *testing.T) {
+	t.Run("DOTTED NAMES", func(t *testing.T) {
+		cfg, err := LoadBytes([]byte(`{"loggers": {"payments.stripe": {"level": "debug", "metadata": {"team": "payments"}, "driver": "stderr"}}}`), "json")
+		assert.NilError(t, err)
+		assert.DeepEqual(t, cfg.Loggers, map[string]LoggerConfig{
+			"payments.stripe": {Level: "debug", Metadata: map[string]string{"team": "payments"}, Driver: "stderr"},
+		})
+	})
+
+	t.Run("INVALID VALUES", func(t *testing.T) {
+		err := Validate(PkgConfig{Loggers: map[string]LoggerConfig{
+			"payments..stripe": {},
+			"payments":         {Level: "loud", Driver: "file:"},
+		}})
+
+		var errs ValidationErrors
+		assert.Assert(t, errors.As(err, &errs))
+		assert.Equal(t, len(errs), 3)
+		assert.ErrorContains(t, errs[0], `loggers.payments.level: unknown level "loud"`)
+		assert.Equal(t, errs[1].Error(), `loggers.payments.driver: invalid driver "file:", expected stdout, stderr or file:<path>`)
+		assert.Equal(t, errs[2].Error(), `loggers.payments..stripe: invalid logger name "payments..stripe"`)
+	})
+
+	t.Run("UNKNOWN KEYS", func(t *testing.T) {
+		_, err := LoadBytes([]byte(`{"loggers": {"payments": {"levle": "debug"}}}`), "json")
+		assert.ErrorContains(t, err, "loggers.payments.levle: unknown key")
+	})
+}
+
+func TestValidateRedaction(t *testing.T) {
+	cfg, err := LoadBytes([]byte(`{"redaction": {"keys": ["password", "*_token"], "patterns": ["email"], "expressions": ["ssn=\\d+"], "mode": "hash"}}`), "json")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, cfg.Redaction, RedactionConfig{
+		Keys:        []string{"password", "*_token"},
+		Patterns:    []string{"email"},
+		Expressions: []string{`ssn=\d+`},
+		Mode:        "hash",
+	})
+
+	err = Validate(PkgConfig{Redaction: RedactionConfig{Keys: []string{"["}, Patterns: []string{"iban"}, Expressions: []string{"("}, Mode: "blur"}})
+
+	var errs ValidationErrors
+	assert.Assert(t, errors.As(err, &errs))
+	assert.Equal(t, len(errs), 4)
+	assert.Equal(t, errs[0].Error(), `redaction.keys.0: invalid key "["`)
+	assert.Equal(t, errs[1].Error(), `redaction.patterns.0: unknown pattern "iban", expected one of credit_card, bearer_token, email`)
+	assert.ErrorContains(t, errs[2], `redaction.expressions.0: invalid expression`)
+	assert.Equal(t, errs[3].Error(), `redaction.mode: unknown mode "blur", expected one of mask, hash, drop`)
+}
+
+func TestValidateEnrich(t *testing.T) {
+	assert.NilError(t, Validate(PkgConfig{Enrich: EnrichConfig{Fields: EnrichFields}}))
+	assert.Error(t, Validate(PkgConfig{Enrich: EnrichConfig{Fields: []string{"pid", "region"}}}),
+		`enrich.fields.1: unknown field "region", expected one of hostname, pid, service, version, environment, kubernetes`)
+}
+
+func TestValidateMultiline(t *testing.T) {
+	for _, mode := range MultilineModes {
+		assert.NilError(t, Validate(PkgConfig{Formatting: FormattingConfig{LogConfig: LogConfig{Multiline: mode, MultilinePrefix: "  | "}}}))
+	}
+
+	var errs ValidationErrors
+	err := Validate(PkgConfig{Formatting: FormattingConfig{TxConfig: TxConfig{Multiline: "fold", MultilinePrefix: "\n>"}}})
+	assert.Assert(t, errors.As(err, &errs))
+	assert.Equal(t, len(errs), 2)
+	assert.Error(t, errs[0], `formatting.transaction.multiline: unknown mode "fold", expected one of indent, escape, raw`)
+	assert.Error(t, errs[1], `formatting.transaction.multiline_prefix: invalid prefix "\n>", prefixes cannot hold line breaks`)
+}
+
+func TestLocation(t *testing.T) {
+	for name, offset := range map[string]int{"UTC": 0, "+02:00": 7200, "-0530": -19800, "+03": 10800} {
+		loc, err := Location(name)
+		assert.NilError(t, err, name)
+
+		_, actual := time.Date(2024, 1, 1, 0, 0, 0, 0, loc).Zone()
+		assert.Equal(t, actual, offset, name)
+	}
+
+	loc, err := Location("")
+	assert.NilError(t, err)
+	assert.Equal(t, loc, time.Local)
+
+	loc, err = Location("Europe/Berlin")
+	assert.NilError(t, err)
+	assert.Equal(t, loc.String(), "Europe/Berlin")
+
+	_, err = Location("+25:00")
+	assert.ErrorContains(t, err, `invalid timezone offset "+25:00"`)
+
+	err = Validate(PkgConfig{Formatting: FormattingConfig{TxConfig: TxConfig{Timezone: "Mars/Olympus"}}})
+	assert.ErrorContains(t, err, `formatting.transaction.timezone: unknown timezone "Mars/Olympus"`)
+}
@@ -0,0 +1,72 @@
+package drivers
+
+import (
+	"github.com/canghel3/telemetry/config"
+	"github.com/canghel3/telemetry/encoder"
+	"reflect"
+	"sync"
+)
+
+// Flusher is implemented by drivers that buffer entries before delivering them.
+// Flush blocks until every buffered entry has been delivered or has failed to be.
+type Flusher interface {
+	Flush() error
+}
+
+// EntryWriter is implemented by drivers that serialize entries themselves.
+// Such drivers receive every entry through WriteEntry instead of the bytes rendered by the encoder of the output,
+// after processors and redaction were applied. Entries of a transaction carry its id.
+// The entry and its content are reused once WriteEntry returns, drivers keeping them must copy them.
+type EntryWriter interface {
+	WriteEntry(e *encoder.Entry) error
+}
+
+// FormattedEntryWriter is implemented by entry writers rendering entries themselves, e.g. into lines.
+// Outputs call WriteFormattedEntry instead of WriteEntry with their formatting configuration,
+// the transaction one for entries of a transaction, so that the entries are rendered as the output is configured.
+type FormattedEntryWriter interface {
+	WriteFormattedEntry(e *encoder.Entry, cfg config.LogConfig) error
+}
+
+var (
+	registry sync.Mutex
+	//flushers holds the registered drivers, so that they can be flushed before the process terminates.
+	flushers = make(map[Flusher]struct{})
+)
+
+// Register keeps track of the flusher until Unregister is called, making it part of Registered.
+// Outputs register the drivers they write to, the drivers of this package unregister themselves on Close.
+// Flushers that are not comparable are ignored.
+func Register(f Flusher) {
+	if !reflect.TypeOf(f).Comparable() {
+		return
+	}
+
+	registry.Lock()
+	defer registry.Unlock()
+	flushers[f] = struct{}{}
+}
+
+// Unregister stops tracking the flusher. Custom drivers should call it once closed.
+func Unregister(f Flusher) {
+	if !reflect.TypeOf(f).Comparable() {
+		return
+	}
+
+	registry.Lock()
+	defer registry.Unlock()
+	delete(flushers, f)
+}
+
+// Registered returns the flushers registered and not unregistered yet.
+func Registered() []Flusher {
+	registry.Lock()
+	defer registry.Unlock()
+
+	registered := make([]Flusher, 0, len(flushers))
+	for f := range flushers {
+		registered = append(registered, f)
+	}
+
+	return registered
+}
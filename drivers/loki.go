@@ -0,0 +1,328 @@
+package drivers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/canghel3/telemetry/config"
+	"github.com/canghel3/telemetry/encoder"
+	"github.com/canghel3/telemetry/level"
+	"github.com/golang/snappy"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LokiPushPath is the path of the push API of Loki, appended to URLs without a path.
+const LokiPushPath = "/loki/api/v1/push"
+
+// LokiOptions configures a LokiDriver. Zero values use the defaults.
+type LokiOptions struct {
+	// Labels are added to every stream, e.g. job or app.
+	Labels map[string]string
+	// LabelKeys are the metadata keys whose values become stream labels rather than staying in the line.
+	// Keep them few and of low cardinality, every distinct label set is a stream in Loki.
+	// Characters not allowed in label names, such as the dot of k8s.pod, are replaced by underscores.
+	LabelKeys []string
+	// Tenant is sent in the X-Scope-OrgID header of multi-tenant Loki deployments.
+	Tenant string
+	// Protobuf pushes snappy compressed protobuf, JSON otherwise.
+	Protobuf bool
+	// Encoder renders the lines. Defaults to encoder.Text.
+	Encoder encoder.Encoder
+	// Config configures the Encoder, overriding the formatting configuration of the outputs writing to the driver.
+	// Entries written with WriteEntry, outside of an output, default to the log configuration of config.Default.
+	Config *config.LogConfig
+	// Webhook configures the batching, authentication and retries of the pushes.
+	// Gzip only applies to JSON pushes, the Content-Type and X-Scope-OrgID headers are set by the driver.
+	Webhook WebhookOptions
+}
+
+// LokiDriver pushes entries to the push API of Grafana Loki. The level of the entries and the metadata values of
+// LabelKeys are stream labels, the rest of the entry is rendered by the encoder into the line.
+// The entries of a batch are grouped by label set into streams.
+// It is safe for concurrent use.
+//
+//	driver := drivers.NewLokiDriver("http://loki:3100", drivers.LokiOptions{
+//		Labels:    map[string]string{"app": "payments"},
+//		LabelKeys: []string{"environment"},
+//		Protobuf:  true,
+//	})
+//	logger := log.OutputDriver(driver)
+type LokiDriver struct {
+	options LokiOptions
+	//labels maps the label keys to their sanitized label names.
+	labels  map[string]string
+	webhook *WebhookDriver
+}
+
+// NewLokiDriver returns a driver pushing to the Loki at the URL, the push path being appended when the URL has no path.
+func NewLokiDriver(address string, options LokiOptions) *LokiDriver {
+	if options.Encoder == nil {
+		options.Encoder = encoder.Text()
+	}
+
+	if u, err := url.Parse(address); err == nil && (u.Path == "" || u.Path == "/") {
+		u.Path = LokiPushPath
+		address = u.String()
+	}
+
+	l := &LokiDriver{options: options, labels: make(map[string]string, len(options.LabelKeys))}
+	for _, key := range options.LabelKeys {
+		l.labels[key] = labelName(key)
+	}
+
+	webhook := options.Webhook
+	webhook.Headers = webhook.Headers.Clone()
+	if webhook.Headers == nil {
+		webhook.Headers = http.Header{}
+	}
+
+	if len(options.Tenant) > 0 {
+		webhook.Headers.Set("X-Scope-OrgID", options.Tenant)
+	}
+
+	if options.Protobuf {
+		webhook.Headers.Set("Content-Type", "application/x-protobuf")
+		//protobuf pushes are compressed with snappy instead
+		webhook.Gzip = false
+		webhook.Body = lokiProtobuf
+	} else {
+		webhook.Headers.Set("Content-Type", "application/json")
+		webhook.Body = lokiJSON
+	}
+
+	l.webhook = NewWebhookDriver(address, webhook)
+	return l
+}
+
+// WriteEntry queues the entry to be pushed to its stream, its line rendered with the log configuration of config.Default
+// unless LokiOptions.Config is set.
+func (l *LokiDriver) WriteEntry(e *encoder.Entry) error {
+	return l.WriteFormattedEntry(e, config.Default().Formatting.LogConfig)
+}
+
+// WriteFormattedEntry queues the entry to be pushed to its stream, its line rendered with the configuration
+// of the output unless LokiOptions.Config is set.
+func (l *LokiDriver) WriteFormattedEntry(e *encoder.Entry, cfg config.LogConfig) error {
+	if l.options.Config != nil {
+		cfg = *l.options.Config
+	}
+
+	labels := l.streamLabels(e.Level, e.Metadata)
+
+	//the label keys are left out of the line
+	line := *e
+	if len(l.labels) > 0 && len(e.Metadata) > 0 {
+		line.Metadata = make(map[any]any, len(e.Metadata))
+		for k, v := range e.Metadata {
+			if _, ok := l.labels[fmt.Sprint(k)]; !ok {
+				line.Metadata[k] = v
+			}
+		}
+	}
+
+	var buffer bytes.Buffer
+	if cfg.FormattingDisabled {
+		buffer.Write(line.Content)
+	} else {
+		l.options.Encoder.Encode(&buffer, &line, cfg)
+	}
+	_, err := l.webhook.Write(lokiRecord(labels, e.Time, bytes.TrimSuffix(buffer.Bytes(), []byte("\n"))))
+	return err
+}
+
+// Write queues p as a line of the stream holding only the configured labels, for writers unaware of entries.
+func (l *LokiDriver) Write(p []byte) (int, error) {
+	_, err := l.webhook.Write(lokiRecord(l.streamLabels(nil, nil), time.Now(), bytes.TrimSuffix(p, []byte("\n"))))
+	return len(p), err
+}
+
+// Flush pushes the pending entries, see WebhookDriver.Flush.
+func (l *LokiDriver) Flush() error {
+	return l.webhook.Flush()
+}
+
+// Close pushes the pending entries and stops the driver, see WebhookDriver.Close.
+func (l *LokiDriver) Close() error {
+	Unregister(l)
+	return l.webhook.Close()
+}
+
+// Dropped returns the number of entries dropped because Loki was falling behind or failed.
+func (l *LokiDriver) Dropped() uint64 {
+	return l.webhook.Dropped()
+}
+
+// streamLabels returns the labels of the stream of an entry with the level and metadata, sorted by name.
+func (l *LokiDriver) streamLabels(lvl level.Level, metadata map[any]any) [][2]string {
+	labels := make([][2]string, 0, len(l.options.Labels)+len(l.labels)+1)
+	for name, value := range l.options.Labels {
+		//the level of the entry takes precedence over a configured level label
+		if lvl == nil || labelName(name) != "level" {
+			labels = append(labels, [2]string{labelName(name), value})
+		}
+	}
+
+	if lvl != nil {
+		labels = append(labels, [2]string{"level", strings.ToLower(lvl.Type())})
+	}
+
+	for k, v := range metadata {
+		if name, ok := l.labels[fmt.Sprint(k)]; ok {
+			labels = append(labels, [2]string{name, fmt.Sprint(encoder.Resolve(v))})
+		}
+	}
+
+	slices.SortFunc(labels, func(a, b [2]string) int { return strings.Compare(a[0], b[0]) })
+	return labels
+}
+
+// labelName replaces the characters not allowed in Loki label names by underscores.
+func labelName(key string) string {
+	name := []byte(key)
+	for i, c := range name {
+		letter := c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+		if !letter && (i == 0 || c < '0' || c > '9') {
+			name[i] = '_'
+		}
+	}
+
+	return string(name)
+}
+
+// lokiRecord serializes an entry for the webhook driver batching it: the number of labels,
+// every label name and value prefixed with its length, the timestamp in nanoseconds, then the line.
+func lokiRecord(labels [][2]string, t time.Time, line []byte) []byte {
+	record := binary.AppendUvarint(nil, uint64(len(labels)))
+	for _, label := range labels {
+		for _, s := range label {
+			record = binary.AppendUvarint(record, uint64(len(s)))
+			record = append(record, s...)
+		}
+	}
+
+	record = binary.AppendVarint(record, t.UnixNano())
+	return append(record, line...)
+}
+
+var errInvalidRecord = errors.New("invalid loki record")
+
+// lokiStream is a label set with its entries.
+type lokiStream struct {
+	labels [][2]string
+	times  []int64
+	lines  []string
+}
+
+// lokiStreams parses the records of a batch and groups them by label set, in order of first appearance.
+func lokiStreams(records [][]byte) ([]*lokiStream, error) {
+	var streams []*lokiStream
+	index := make(map[string]*lokiStream)
+
+	for _, record := range records {
+		n, read := binary.Uvarint(record)
+		if read <= 0 {
+			return nil, errInvalidRecord
+		}
+		record = record[read:]
+
+		labels := make([][2]string, n)
+		var key strings.Builder
+		for i := range labels {
+			for j := range labels[i] {
+				length, read := binary.Uvarint(record)
+				if read <= 0 || uint64(len(record)-read) < length {
+					return nil, errInvalidRecord
+				}
+				labels[i][j] = string(record[read : read+int(length)])
+				record = record[read+int(length):]
+
+				key.WriteString(labels[i][j])
+				key.WriteByte(0)
+			}
+		}
+
+		nanos, read := binary.Varint(record)
+		if read <= 0 {
+			return nil, errInvalidRecord
+		}
+
+		stream, ok := index[key.String()]
+		if !ok {
+			stream = &lokiStream{labels: labels}
+			index[key.String()] = stream
+			streams = append(streams, stream)
+		}
+
+		stream.times = append(stream.times, nanos)
+		stream.lines = append(stream.lines, string(record[read:]))
+	}
+
+	return streams, nil
+}
+
+// lokiJSON renders the records of a batch as a JSON push request.
+func lokiJSON(records [][]byte) ([]byte, error) {
+	streams, err := lokiStreams(records)
+	if err != nil {
+		return nil, err
+	}
+
+	type stream struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	}
+
+	push := struct {
+		Streams []stream `json:"streams"`
+	}{Streams: make([]stream, 0, len(streams))}
+
+	for _, s := range streams {
+		labels := make(map[string]string, len(s.labels))
+		for _, label := range s.labels {
+			labels[label[0]] = label[1]
+		}
+
+		values := make([][2]string, len(s.lines))
+		for i := range s.lines {
+			values[i] = [2]string{strconv.FormatInt(s.times[i], 10), s.lines[i]}
+		}
+
+		push.Streams = append(push.Streams, stream{Stream: labels, Values: values})
+	}
+
+	return json.Marshal(push)
+}
+
+// lokiProtobuf renders the records of a batch as a snappy compressed protobuf push request.
+func lokiProtobuf(records [][]byte) ([]byte, error) {
+	streams, err := lokiStreams(records)
+	if err != nil {
+		return nil, err
+	}
+
+	return snappy.Encode(nil, pushRequest(streams)), nil
+}
+
+// selector renders the labels as a Loki stream selector, e.g. {app="payments", level="info"}.
+func selector(labels [][2]string) string {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, label := range labels {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(label[0])
+		b.WriteByte('=')
+		b.WriteString(strconv.Quote(label[1]))
+	}
+	b.WriteByte('}')
+
+	return b.String()
+}
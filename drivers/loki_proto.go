@@ -0,0 +1,65 @@
+package drivers
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+// Protobuf wire types.
+const (
+	wireVarint = 0
+	wireBytes  = 2
+)
+
+// pushRequest encodes the streams as the logproto.PushRequest message of the Loki push API:
+//
+//	message PushRequest { repeated StreamAdapter streams = 1; }
+//	message StreamAdapter { string labels = 1; repeated EntryAdapter entries = 2; }
+//	message EntryAdapter { google.protobuf.Timestamp timestamp = 1; string line = 2; }
+//	message Timestamp { int64 seconds = 1; int32 nanos = 2; }
+func pushRequest(streams []*lokiStream) []byte {
+	var request, stream, entry, timestamp []byte
+	for _, s := range streams {
+		stream = appendString(stream[:0], 1, selector(s.labels))
+		for i, line := range s.lines {
+			t := time.Unix(0, s.times[i])
+
+			timestamp = appendVarint(timestamp[:0], 1, uint64(t.Unix()))
+			timestamp = appendVarint(timestamp, 2, uint64(t.Nanosecond()))
+
+			entry = appendBytes(entry[:0], 1, timestamp)
+			entry = appendString(entry, 2, line)
+			stream = appendBytes(stream, 2, entry)
+		}
+
+		request = appendBytes(request, 1, stream)
+	}
+
+	return request
+}
+
+func appendTag(b []byte, field int, wireType int) []byte {
+	return binary.AppendUvarint(b, uint64(field)<<3|uint64(wireType))
+}
+
+// appendVarint appends a varint field, omitted when zero as in proto3.
+func appendVarint(b []byte, field int, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+
+	b = appendTag(b, field, wireVarint)
+	return binary.AppendUvarint(b, v)
+}
+
+func appendBytes(b []byte, field int, v []byte) []byte {
+	b = appendTag(b, field, wireBytes)
+	b = binary.AppendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+func appendString(b []byte, field int, v string) []byte {
+	b = appendTag(b, field, wireBytes)
+	b = binary.AppendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
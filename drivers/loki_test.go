@@ -0,0 +1,291 @@
+package drivers
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"github.com/canghel3/telemetry/config"
+	"github.com/canghel3/telemetry/encoder"
+	"github.com/canghel3/telemetry/level"
+	"github.com/golang/snappy"
+	"gotest.tools/v3/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// lokiPush is a push request decoded by the fake Loki, whichever its format.
+type lokiPush struct {
+	path    string
+	headers http.Header
+	streams map[string][]lokiValue
+}
+
+type lokiValue struct {
+	time time.Time
+	line string
+}
+
+// newFakeLoki returns a server decoding the JSON and protobuf pushes it receives.
+func newFakeLoki(t *testing.T) (*httptest.Server, func() []lokiPush) {
+	var lock sync.Mutex
+	var pushes []lokiPush
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.Check(t, err)
+
+		push := lokiPush{path: r.URL.Path, headers: r.Header, streams: make(map[string][]lokiValue)}
+		if r.Header.Get("Content-Type") == "application/x-protobuf" {
+			err = decodeProtobufPush(body, push.streams)
+		} else {
+			err = decodeJSONPush(body, push.streams)
+		}
+
+		if !assert.Check(t, err) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		lock.Lock()
+		defer lock.Unlock()
+		pushes = append(pushes, push)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() []lokiPush {
+		lock.Lock()
+		defer lock.Unlock()
+		return append([]lokiPush(nil), pushes...)
+	}
+}
+
+func decodeJSONPush(body []byte, streams map[string][]lokiValue) error {
+	var push struct {
+		Streams []struct {
+			Stream map[string]string `json:"stream"`
+			Values [][2]string       `json:"values"`
+		} `json:"streams"`
+	}
+
+	err := json.Unmarshal(body, &push)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range push.Streams {
+		labels := make([][2]string, 0, len(s.Stream))
+		for name, value := range s.Stream {
+			labels = append(labels, [2]string{name, value})
+		}
+		slices.SortFunc(labels, func(a, b [2]string) int { return strings.Compare(a[0], b[0]) })
+
+		for _, v := range s.Values {
+			var nanos int64
+			err = json.Unmarshal([]byte(v[0]), &nanos)
+			if err != nil {
+				return err
+			}
+			streams[selector(labels)] = append(streams[selector(labels)], lokiValue{time: time.Unix(0, nanos), line: v[1]})
+		}
+	}
+
+	return nil
+}
+
+// decodeProtobufPush decodes a snappy compressed PushRequest.
+func decodeProtobufPush(body []byte, streams map[string][]lokiValue) error {
+	data, err := snappy.Decode(nil, body)
+	if err != nil {
+		return err
+	}
+
+	return protoFields(data, func(field int, stream []byte, _ uint64) error {
+		var labels string
+		var values []lokiValue
+		err := protoFields(stream, func(field int, v []byte, _ uint64) error {
+			if field == 1 {
+				labels = string(v)
+				return nil
+			}
+
+			var value lokiValue
+			var seconds, nanos uint64
+			err := protoFields(v, func(field int, v []byte, _ uint64) error {
+				if field == 2 {
+					value.line = string(v)
+					return nil
+				}
+
+				return protoFields(v, func(field int, _ []byte, n uint64) error {
+					if field == 1 {
+						seconds = n
+					} else {
+						nanos = n
+					}
+					return nil
+				})
+			})
+			value.time = time.Unix(int64(seconds), int64(nanos))
+			values = append(values, value)
+			return err
+		})
+
+		streams[labels] = append(streams[labels], values...)
+		return err
+	})
+}
+
+// protoFields calls fn with every field of the message, with its bytes for length delimited fields and its value for varints.
+func protoFields(message []byte, fn func(field int, v []byte, n uint64) error) error {
+	for len(message) > 0 {
+		tag, read := binary.Uvarint(message)
+		if read <= 0 {
+			return errors.New("invalid tag")
+		}
+		message = message[read:]
+
+		switch tag & 7 {
+		case wireVarint:
+			n, read := binary.Uvarint(message)
+			if read <= 0 {
+				return errors.New("invalid varint")
+			}
+			message = message[read:]
+			if err := fn(int(tag>>3), nil, n); err != nil {
+				return err
+			}
+		case wireBytes:
+			length, read := binary.Uvarint(message)
+			if read <= 0 || uint64(len(message)-read) < length {
+				return errors.New("invalid length")
+			}
+			if err := fn(int(tag>>3), message[read:read+int(length)], 0); err != nil {
+				return err
+			}
+			message = message[read+int(length):]
+		default:
+			return errors.New("unexpected wire type")
+		}
+	}
+
+	return nil
+}
+
+func TestLokiDriver(t *testing.T) {
+	now := time.Date(2024, 3, 2, 10, 0, 0, 123456789, time.UTC)
+	entry := func(l level.Level, content string, metadata map[any]any) *encoder.Entry {
+		return &encoder.Entry{Time: now, Level: l, Content: []byte(content), Metadata: metadata}
+	}
+
+	for name, protobuf := range map[string]bool{"JSON": false, "PROTOBUF": true} {
+		t.Run(name, func(t *testing.T) {
+			server, pushes := newFakeLoki(t)
+			driver := NewLokiDriver(server.URL, LokiOptions{
+				Labels:    map[string]string{"app": "payments"},
+				LabelKeys: []string{"k8s.pod"},
+				Tenant:    "team-a",
+				Protobuf:  protobuf,
+			})
+			defer driver.Close()
+
+			assert.NilError(t, driver.WriteEntry(entry(level.Info(), "started", map[any]any{"k8s.pod": "pod-1", "order": 7})))
+			assert.NilError(t, driver.WriteEntry(entry(level.Error(), "failed", map[any]any{"k8s.pod": "pod-1"})))
+			assert.NilError(t, driver.WriteEntry(entry(level.Info(), "served", map[any]any{"k8s.pod": "pod-1"})))
+			assert.NilError(t, driver.Flush())
+
+			received := pushes()
+			assert.Equal(t, len(received), 1)
+			assert.Equal(t, received[0].path, LokiPushPath)
+			assert.Equal(t, received[0].headers.Get("X-Scope-OrgID"), "team-a")
+
+			//entries are grouped by label set, the label keys are left out of the line
+			streams := received[0].streams
+			assert.Equal(t, len(streams), 2)
+
+			info := streams[`{app="payments", k8s_pod="pod-1", level="info"}`]
+			assert.Equal(t, len(info), 2)
+			assert.Assert(t, info[0].time.Equal(now))
+			assert.Assert(t, strings.HasSuffix(info[0].line, " INFO order:7 started"), info[0].line)
+			assert.Assert(t, !strings.Contains(info[0].line, "pod-1"), info[0].line)
+			assert.Assert(t, strings.HasSuffix(info[1].line, " INFO served"), info[1].line)
+
+			errorStream := streams[`{app="payments", k8s_pod="pod-1", level="error"}`]
+			assert.Equal(t, len(errorStream), 1)
+			assert.Assert(t, strings.HasSuffix(errorStream[0].line, " ERROR failed"), errorStream[0].line)
+		})
+	}
+
+	t.Run("WRITE", func(t *testing.T) {
+		server, pushes := newFakeLoki(t)
+		driver := NewLokiDriver(server.URL+"/custom/push", LokiOptions{Labels: map[string]string{"app": "payments"}})
+		defer driver.Close()
+
+		_, err := driver.Write([]byte("written by an unaware writer\n"))
+		assert.NilError(t, err)
+		assert.NilError(t, driver.Flush())
+
+		received := pushes()
+		assert.Equal(t, len(received), 1)
+		assert.Equal(t, received[0].path, "/custom/push")
+		assert.Equal(t, received[0].streams[`{app="payments"}`][0].line, "written by an unaware writer")
+	})
+}
+
+func TestLokiFormatting(t *testing.T) {
+	now := time.Date(2024, 3, 2, 10, 0, 0, 0, time.UTC)
+	e := &encoder.Entry{Time: now, Level: level.Info(), Content: []byte("first\nsecond")}
+	cfg := config.LogConfig{Timestamp: "15:04:05", Timezone: "+02:00", Multiline: "escape"}
+
+	t.Run("CONFIGURATION OF THE OUTPUT", func(t *testing.T) {
+		server, pushes := newFakeLoki(t)
+		driver := NewLokiDriver(server.URL, LokiOptions{})
+		defer driver.Close()
+
+		assert.NilError(t, driver.WriteFormattedEntry(e, cfg))
+		assert.NilError(t, driver.Flush())
+		assert.Equal(t, pushes()[0].streams[`{level="info"}`][0].line, `12:00:00 INFO first\nsecond`)
+	})
+
+	t.Run("CONFIGURATION OF THE OPTIONS", func(t *testing.T) {
+		server, pushes := newFakeLoki(t)
+		driver := NewLokiDriver(server.URL, LokiOptions{Config: &config.LogConfig{FormattingDisabled: true}})
+		defer driver.Close()
+
+		assert.NilError(t, driver.WriteFormattedEntry(e, cfg))
+		assert.NilError(t, driver.Flush())
+		assert.Equal(t, pushes()[0].streams[`{level="info"}`][0].line, "first\nsecond")
+	})
+}
+
+func TestLabelName(t *testing.T) {
+	assert.Equal(t, labelName("k8s.pod"), "k8s_pod")
+	assert.Equal(t, labelName("9lives"), "_lives")
+	assert.Equal(t, labelName("service_name"), "service_name")
+}
+
+func TestLokiProtobufCompressed(t *testing.T) {
+	labels := [][2]string{{"app", "payments"}}
+	var records [][]byte
+	for i := 0; i < 100; i++ {
+		records = append(records, lokiRecord(labels, time.Unix(0, int64(i)), []byte("2024-03-02 10:00:00 INFO order paid")))
+	}
+
+	body, err := lokiProtobuf(records)
+	assert.NilError(t, err)
+
+	streams, err := lokiStreams(records)
+	assert.NilError(t, err)
+	uncompressed := pushRequest(streams)
+	assert.Assert(t, len(body) < len(uncompressed)/4, "%d bytes compressed to %d", len(uncompressed), len(body))
+
+	decoded, err := snappy.Decode(nil, body)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, decoded, uncompressed)
+}
@@ -0,0 +1,460 @@
+package drivers
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Framing tells how a NetworkDriver delimits the entries it sends.
+type Framing int
+
+const (
+	// NewlineFraming ends every entry with a line feed, unless it already ends with one.
+	NewlineFraming Framing = iota
+	// LengthFraming prefixes every entry with its length in bytes as a 4 byte big endian integer.
+	LengthFraming
+)
+
+// ConnState is the state of the connection of a NetworkDriver.
+type ConnState int32
+
+const (
+	// Connecting is the state until the first connection is established.
+	Connecting ConnState = iota
+	// Connected is the state while entries are written to the connection.
+	Connected
+	// Disconnected is the state after a connection failed, until it is established again.
+	Disconnected
+	// Closed is the state once the driver was closed.
+	Closed
+)
+
+func (s ConnState) String() string {
+	switch s {
+	case Connecting:
+		return "connecting"
+	case Connected:
+		return "connected"
+	case Disconnected:
+		return "disconnected"
+	case Closed:
+		return "closed"
+	default:
+		return fmt.Sprintf("ConnState(%d)", int32(s))
+	}
+}
+
+// ErrClosed is returned when writing to a closed driver.
+var ErrClosed = errors.New("driver closed")
+
+// MaxDatagramSize is the largest frame a NetworkDriver sends over udp and unixgram, the largest UDP payload over IPv4.
+// Larger entries can never be sent as one datagram and are dropped by Write.
+const MaxDatagramSize = 65507
+
+// NetworkOptions configures a NetworkDriver. Zero values use the defaults.
+type NetworkOptions struct {
+	// Framing delimits the entries. Defaults to NewlineFraming.
+	Framing Framing
+	// TLS secures stream connections when set, see LoadTLSConfig.
+	TLS *tls.Config
+	// DialTimeout bounds every connection attempt. Defaults to 5 seconds.
+	DialTimeout time.Duration
+	// WriteTimeout bounds every write. Defaults to 5 seconds.
+	WriteTimeout time.Duration
+	// MinBackoff is the delay before the first reconnection attempt, doubled after every failed attempt. Defaults to 100 milliseconds.
+	MinBackoff time.Duration
+	// MaxBackoff bounds the delay between reconnection attempts. Defaults to 30 seconds.
+	MaxBackoff time.Duration
+	// BufferSize is the number of bytes of entries kept while disconnected, the oldest ones being dropped first. Defaults to 1 MiB.
+	BufferSize int
+}
+
+func (o NetworkOptions) withDefaults() NetworkOptions {
+	if o.DialTimeout <= 0 {
+		o.DialTimeout = 5 * time.Second
+	}
+
+	if o.WriteTimeout <= 0 {
+		o.WriteTimeout = 5 * time.Second
+	}
+
+	if o.MinBackoff <= 0 {
+		o.MinBackoff = 100 * time.Millisecond
+	}
+
+	if o.MaxBackoff < o.MinBackoff {
+		o.MaxBackoff = max(30*time.Second, o.MinBackoff)
+	}
+
+	if o.BufferSize <= 0 {
+		o.BufferSize = 1 << 20
+	}
+
+	return o
+}
+
+// NetworkDriver writes entries to a TCP, UDP or unix socket. Every write is sent as one frame,
+// so the entries of a transaction rendered as a block share a frame.
+//
+// The connection is established in the background. While it is down, entries are kept in a bounded buffer
+// and the driver reconnects with exponential backoff, sending the buffered entries first once connected.
+// An entry whose write failed midway is sent again in full, so the receiving end may see its beginning twice.
+// It is safe for concurrent use.
+type NetworkDriver struct {
+	network string
+	address string
+	options NetworkOptions
+
+	lock sync.Mutex
+	conn net.Conn
+	//pending holds the frames written while disconnected, oldest first.
+	pending [][]byte
+	size    int
+	//reconnecting is set while the reconnection loop runs.
+	reconnecting bool
+	lastErr      error
+
+	state   atomic.Int32
+	dropped atomic.Uint64
+	done    chan struct{}
+}
+
+// NewNetworkDriver returns a driver writing to the address on the network, one of tcp, tcp4, tcp6, udp, udp4, udp6, unix or unixgram.
+// It connects in the background, entries written in the meantime are buffered.
+//
+//	driver := drivers.NewNetworkDriver("tcp", "collector:5170", drivers.NetworkOptions{Framing: drivers.LengthFraming})
+//	logger := log.OutputDriver(driver)
+func NewNetworkDriver(network, address string, options NetworkOptions) *NetworkDriver {
+	n := &NetworkDriver{
+		network: network,
+		address: address,
+		options: options.withDefaults(),
+		done:    make(chan struct{}),
+	}
+
+	n.lock.Lock()
+	n.reconnect(0)
+	n.lock.Unlock()
+	return n
+}
+
+// Write sends p as one frame, or buffers it while disconnected.
+// An error is only returned when the driver is closed or when buffering dropped entries.
+func (n *NetworkDriver) Write(p []byte) (int, error) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if n.State() == Closed {
+		return 0, ErrClosed
+	}
+
+	//the caller reuses p once Write returns, the frame is a copy
+	frame := n.frame(p)
+	if n.datagram() && len(frame) > MaxDatagramSize {
+		n.dropped.Add(1)
+		return len(p), fmt.Errorf("dropped an entry of %d bytes, exceeding the %d bytes of a %s datagram", len(frame), MaxDatagramSize, n.network)
+	}
+
+	if n.conn != nil {
+		err := n.send(frame)
+		if err == nil {
+			return len(p), nil
+		}
+
+		n.disconnect(err)
+	}
+
+	dropped := n.buffer(frame)
+	if dropped > 0 {
+		return len(p), fmt.Errorf("%s %s is unreachable, dropped %d buffered entries: %w", n.network, n.address, dropped, n.lastErr)
+	}
+
+	return len(p), nil
+}
+
+// Flush delivers the buffered entries. When disconnected, it attempts to connect once without waiting for the backoff.
+func (n *NetworkDriver) Flush() error {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if len(n.pending) == 0 || n.State() == Closed {
+		return nil
+	}
+
+	conn, err := n.dial()
+	if err != nil {
+		return fmt.Errorf("failed to deliver %d buffered entries to %s %s: %w", len(n.pending), n.network, n.address, err)
+	}
+
+	return n.connected(conn)
+}
+
+// Close closes the connection and stops reconnecting. Buffered entries are dropped.
+func (n *NetworkDriver) Close() error {
+	Unregister(n)
+
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if n.State() == Closed {
+		return nil
+	}
+
+	n.state.Store(int32(Closed))
+	close(n.done)
+	n.dropped.Add(uint64(len(n.pending)))
+	n.pending, n.size = nil, 0
+
+	if n.conn == nil {
+		return nil
+	}
+
+	err := n.conn.Close()
+	n.conn = nil
+	return err
+}
+
+// State returns the state of the connection.
+func (n *NetworkDriver) State() ConnState {
+	return ConnState(n.state.Load())
+}
+
+// Dropped returns the number of entries dropped because the buffer was full, they could not be sent
+// on a fresh connection, they exceeded MaxDatagramSize or the driver was closed.
+func (n *NetworkDriver) Dropped() uint64 {
+	return n.dropped.Load()
+}
+
+// Err returns the error that caused the last disconnection or failed connection attempt, nil once connected.
+func (n *NetworkDriver) Err() error {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	return n.lastErr
+}
+
+// frame returns a copy of p delimited according to the framing.
+func (n *NetworkDriver) frame(p []byte) []byte {
+	if n.options.Framing == LengthFraming {
+		frame := make([]byte, 4, 4+len(p))
+		binary.BigEndian.PutUint32(frame, uint32(len(p)))
+		return append(frame, p...)
+	}
+
+	frame := make([]byte, len(p), len(p)+1)
+	copy(frame, p)
+	if len(p) == 0 || p[len(p)-1] != '\n' {
+		frame = append(frame, '\n')
+	}
+
+	return frame
+}
+
+// datagram reports whether every frame is sent as one datagram, bounding its size.
+func (n *NetworkDriver) datagram() bool {
+	switch n.network {
+	case "udp", "udp4", "udp6", "unixgram":
+		return true
+	default:
+		return false
+	}
+}
+
+// send writes the frame to the connection. The lock must be held.
+func (n *NetworkDriver) send(frame []byte) error {
+	err := n.conn.SetWriteDeadline(time.Now().Add(n.options.WriteTimeout))
+	if err != nil {
+		return err
+	}
+
+	_, err = n.conn.Write(frame)
+	return err
+}
+
+// buffer keeps the frame until the connection is established again, dropping the oldest frames beyond the buffer size.
+// It returns the number of dropped frames. The lock must be held.
+func (n *NetworkDriver) buffer(frame []byte) int {
+	n.pending = append(n.pending, frame)
+	n.size += len(frame)
+
+	dropped := 0
+	for n.size > n.options.BufferSize {
+		n.size -= len(n.pending[dropped])
+		n.pending[dropped] = nil
+		dropped++
+	}
+
+	if dropped > 0 {
+		n.pending = n.pending[dropped:]
+		n.dropped.Add(uint64(dropped))
+	}
+
+	return dropped
+}
+
+// disconnect closes the failed connection and starts reconnecting. The lock must be held.
+func (n *NetworkDriver) disconnect(err error) {
+	n.conn.Close()
+	n.conn = nil
+	n.lastErr = err
+	n.state.Store(int32(Disconnected))
+	n.reconnect(n.options.MinBackoff)
+}
+
+// reconnect starts the reconnection loop unless it is running. The lock must be held.
+func (n *NetworkDriver) reconnect(delay time.Duration) {
+	if n.reconnecting {
+		return
+	}
+
+	n.reconnecting = true
+	go n.loop(delay)
+}
+
+// loop connects with exponential backoff until connected or closed.
+func (n *NetworkDriver) loop(delay time.Duration) {
+	for {
+		if delay > 0 {
+			timer := time.NewTimer(delay)
+			select {
+			case <-n.done:
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		}
+
+		//dialing does not hold the lock, so that writes are buffered meanwhile rather than blocked
+		conn, err := n.dial()
+
+		n.lock.Lock()
+		if n.State() == Closed || n.conn != nil {
+			//closed meanwhile, or connected by Flush
+			n.reconnecting = false
+			n.lock.Unlock()
+			if conn != nil {
+				conn.Close()
+			}
+			return
+		}
+
+		if err == nil {
+			err = n.connected(conn)
+		}
+
+		if err == nil {
+			n.reconnecting = false
+			n.lock.Unlock()
+			return
+		}
+
+		n.lastErr = err
+		n.lock.Unlock()
+
+		delay = min(max(2*delay, n.options.MinBackoff), n.options.MaxBackoff)
+	}
+}
+
+// dial establishes a connection, secured by TLS when configured.
+func (n *NetworkDriver) dial() (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), n.options.DialTimeout)
+	defer cancel()
+
+	dialer := &net.Dialer{}
+	if n.options.TLS != nil {
+		return (&tls.Dialer{NetDialer: dialer, Config: n.options.TLS}).DialContext(ctx, n.network, n.address)
+	}
+
+	return dialer.DialContext(ctx, n.network, n.address)
+}
+
+// connected sends the buffered frames to the new connection and makes it the connection of the driver.
+// When a frame cannot be sent, the connection is closed and the frame is dropped, since a frame failing
+// on a fresh connection could otherwise block every frame behind it forever. The lock must be held.
+func (n *NetworkDriver) connected(conn net.Conn) error {
+	n.conn = conn
+	for len(n.pending) > 0 {
+		err := n.send(n.pending[0])
+		if err != nil {
+			conn.Close()
+			n.conn = nil
+			n.dropped.Add(1)
+		}
+
+		n.size -= len(n.pending[0])
+		n.pending[0] = nil
+		n.pending = n.pending[1:]
+
+		if err != nil {
+			return err
+		}
+	}
+
+	n.pending = nil
+	n.lastErr = nil
+	n.state.Store(int32(Connected))
+	go n.watch(conn)
+	return nil
+}
+
+// watch reads the connection until it fails, so that a connection closed by the peer is noticed
+// before entries are written to it rather than after they were lost.
+func (n *NetworkDriver) watch(conn net.Conn) {
+	var discard [512]byte
+	for {
+		_, err := conn.Read(discard[:])
+		if err == nil {
+			continue
+		}
+
+		n.lock.Lock()
+		if n.conn == conn {
+			n.disconnect(err)
+		}
+		n.lock.Unlock()
+		return
+	}
+}
+
+// LoadTLSConfig returns a TLS configuration trusting the certificate authorities of the PEM encoded caFile,
+// in addition to the system ones, and presenting the client certificate of the PEM encoded certFile and keyFile.
+// Empty file names are skipped.
+func LoadTLSConfig(caFile, certFile, keyFile string) (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if len(caFile) > 0 {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in %s", caFile)
+		}
+		cfg.RootCAs = pool
+	}
+
+	if len(certFile) > 0 || len(keyFile) > 0 {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return cfg, nil
+}
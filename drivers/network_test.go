@@ -0,0 +1,274 @@
+package drivers
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"encoding/pem"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/poll"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// readLines reads n lines from the first connection accepted by the listener.
+func readLines(t *testing.T, listener net.Listener, n int) []string {
+	t.Helper()
+
+	conn, err := listener.Accept()
+	assert.NilError(t, err)
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	reader := bufio.NewReader(conn)
+
+	var lines []string
+	for len(lines) < n {
+		line, err := reader.ReadString('\n')
+		assert.NilError(t, err)
+		lines = append(lines, line)
+	}
+
+	return lines
+}
+
+func waitForState(t *testing.T, driver *NetworkDriver, state ConnState) {
+	t.Helper()
+
+	poll.WaitOn(t, func(poll.LogT) poll.Result {
+		if driver.State() == state {
+			return poll.Success()
+		}
+
+		return poll.Continue("state is %s", driver.State())
+	}, poll.WithTimeout(5*time.Second), poll.WithDelay(5*time.Millisecond))
+}
+
+func TestNetworkDriver(t *testing.T) {
+	t.Run("TCP", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.NilError(t, err)
+		defer listener.Close()
+
+		driver := NewNetworkDriver("tcp", listener.Addr().String(), NetworkOptions{})
+		defer driver.Close()
+
+		//entries written before the connection is established are buffered
+		_, err = driver.Write([]byte("first\n"))
+		assert.NilError(t, err)
+		_, err = driver.Write([]byte("second"))
+		assert.NilError(t, err)
+
+		lines := readLines(t, listener, 2)
+		assert.DeepEqual(t, lines, []string{"first\n", "second\n"})
+		assert.Equal(t, driver.State(), Connected)
+	})
+
+	t.Run("UNIX LENGTH FRAMING", func(t *testing.T) {
+		address := filepath.Join(t.TempDir(), "telemetry.sock")
+		listener, err := net.Listen("unix", address)
+		assert.NilError(t, err)
+		defer listener.Close()
+
+		driver := NewNetworkDriver("unix", address, NetworkOptions{Framing: LengthFraming})
+		defer driver.Close()
+		waitForState(t, driver, Connected)
+
+		_, err = driver.Write([]byte("multi\nline\n"))
+		assert.NilError(t, err)
+
+		conn, err := listener.Accept()
+		assert.NilError(t, err)
+		defer conn.Close()
+
+		var length uint32
+		assert.NilError(t, binary.Read(conn, binary.BigEndian, &length))
+		frame := make([]byte, length)
+		_, err = io.ReadFull(conn, frame)
+		assert.NilError(t, err)
+		assert.Equal(t, string(frame), "multi\nline\n")
+	})
+
+	t.Run("UDP", func(t *testing.T) {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		assert.NilError(t, err)
+		defer conn.Close()
+
+		driver := NewNetworkDriver("udp", conn.LocalAddr().String(), NetworkOptions{})
+		defer driver.Close()
+		waitForState(t, driver, Connected)
+
+		_, err = driver.Write([]byte("datagram"))
+		assert.NilError(t, err)
+
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		datagram := make([]byte, 64)
+		n, _, err := conn.ReadFrom(datagram)
+		assert.NilError(t, err)
+		assert.Equal(t, string(datagram[:n]), "datagram\n")
+	})
+
+	t.Run("UDP OVERSIZED ENTRY", func(t *testing.T) {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		assert.NilError(t, err)
+		defer conn.Close()
+
+		driver := NewNetworkDriver("udp", conn.LocalAddr().String(), NetworkOptions{})
+		defer driver.Close()
+		waitForState(t, driver, Connected)
+
+		//an entry that can never fit in a datagram is dropped rather than blocking the entries after it
+		_, err = driver.Write(make([]byte, 70<<10))
+		assert.ErrorContains(t, err, "dropped an entry of 71681 bytes")
+		assert.Equal(t, driver.Dropped(), uint64(1))
+		assert.Equal(t, driver.State(), Connected)
+
+		_, err = driver.Write([]byte("after"))
+		assert.NilError(t, err)
+
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		datagram := make([]byte, 64)
+		n, _, err := conn.ReadFrom(datagram)
+		assert.NilError(t, err)
+		assert.Equal(t, string(datagram[:n]), "after\n")
+	})
+
+	t.Run("RECONNECT", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.NilError(t, err)
+		address := listener.Addr().String()
+
+		driver := NewNetworkDriver("tcp", address, NetworkOptions{MinBackoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond})
+		defer driver.Close()
+		waitForState(t, driver, Connected)
+
+		_, err = driver.Write([]byte("before outage"))
+		assert.NilError(t, err)
+		assert.DeepEqual(t, readLines(t, listener, 1), []string{"before outage\n"})
+		listener.Close()
+		waitForState(t, driver, Disconnected)
+		assert.Assert(t, driver.Err() != nil)
+
+		_, err = driver.Write([]byte("during outage"))
+		assert.NilError(t, err)
+
+		listener, err = net.Listen("tcp", address)
+		assert.NilError(t, err)
+		defer listener.Close()
+
+		assert.DeepEqual(t, readLines(t, listener, 1), []string{"during outage\n"})
+		waitForState(t, driver, Connected)
+		assert.NilError(t, driver.Err())
+	})
+
+	t.Run("BOUNDED BUFFER", func(t *testing.T) {
+		//an address nothing listens on
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.NilError(t, err)
+		address := listener.Addr().String()
+		listener.Close()
+
+		driver := NewNetworkDriver("tcp", address, NetworkOptions{BufferSize: 20, MinBackoff: time.Hour})
+		defer driver.Close()
+
+		for _, entry := range []string{"entry 1", "entry 2"} {
+			_, err = driver.Write([]byte(entry))
+			assert.NilError(t, err)
+		}
+
+		_, err = driver.Write([]byte("entry 3"))
+		assert.ErrorContains(t, err, "dropped 1 buffered entries")
+		assert.Equal(t, driver.Dropped(), uint64(1))
+		assert.ErrorContains(t, driver.Flush(), "failed to deliver 2 buffered entries")
+
+		listener, err = net.Listen("tcp", address)
+		assert.NilError(t, err)
+		defer listener.Close()
+
+		assert.NilError(t, driver.Flush())
+		assert.DeepEqual(t, readLines(t, listener, 2), []string{"entry 2\n", "entry 3\n"})
+	})
+
+	t.Run("TLS", func(t *testing.T) {
+		dir := t.TempDir()
+		certFile, keyFile := writeCertificate(t, dir)
+
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		assert.NilError(t, err)
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		assert.NilError(t, err)
+		clientCAs := x509.NewCertPool()
+		clientCAs.AddCert(leaf)
+
+		listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			ClientAuth:   tls.RequireAndVerifyClientCert,
+			ClientCAs:    clientCAs,
+		})
+		assert.NilError(t, err)
+		defer listener.Close()
+
+		cfg, err := LoadTLSConfig(certFile, certFile, keyFile)
+		assert.NilError(t, err)
+
+		driver := NewNetworkDriver("tcp", listener.Addr().String(), NetworkOptions{TLS: cfg})
+		defer driver.Close()
+
+		_, err = driver.Write([]byte("secured"))
+		assert.NilError(t, err)
+		assert.DeepEqual(t, readLines(t, listener, 1), []string{"secured\n"})
+	})
+
+	t.Run("CLOSED", func(t *testing.T) {
+		driver := NewNetworkDriver("tcp", "127.0.0.1:1", NetworkOptions{MinBackoff: time.Hour})
+		_, err := driver.Write([]byte("dropped"))
+		assert.NilError(t, err)
+
+		assert.NilError(t, driver.Close())
+		assert.Equal(t, driver.State(), Closed)
+		assert.Equal(t, driver.Dropped(), uint64(1))
+
+		_, err = driver.Write([]byte("after close"))
+		assert.ErrorIs(t, err, ErrClosed)
+	})
+}
+
+// writeCertificate writes a self-signed certificate for 127.0.0.1, valid for servers and clients, and its key to dir.
+func writeCertificate(t *testing.T, dir string) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NilError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "telemetry"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NilError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.NilError(t, err)
+
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	assert.NilError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	assert.NilError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certFile, keyFile
+}
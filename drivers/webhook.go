@@ -0,0 +1,407 @@
+package drivers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// WebhookOptions configures a WebhookDriver. Zero values use the defaults.
+type WebhookOptions struct {
+	// BatchSize is the number of entries sent in one request. Defaults to 100.
+	BatchSize int
+	// BatchInterval is the longest an entry waits for its batch to fill up before it is sent. Defaults to 1 second.
+	BatchInterval time.Duration
+	// Headers are set on every request. The Content-Type defaults to text/plain; charset=utf-8.
+	Headers http.Header
+	// Gzip compresses the request bodies.
+	Gzip bool
+	// BearerToken authenticates the requests with the Authorization: Bearer header.
+	BearerToken string
+	// Username and Password authenticate the requests with HTTP basic authentication.
+	Username string
+	Password string
+	// Body renders the request body from the encoded entries of a batch. Defaults to the concatenation of the entries.
+	Body func(entries [][]byte) ([]byte, error)
+	// MaxRetries is the number of times a request failing with a network error, 429 or a 5xx status is retried.
+	// Defaults to 3, a negative value disables retries.
+	MaxRetries int
+	// MinBackoff and MaxBackoff bound the random delay before a retry, whose upper bound starts at MinBackoff
+	// and doubles with every attempt.
+	// A Retry-After header in the response takes precedence, capped at MaxBackoff. Default to 500 milliseconds and 30 seconds.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// MaxPending is the number of entries waiting to be sent, beyond which the oldest batches are dropped. Defaults to 10000.
+	MaxPending int
+	// Client sends the requests. Defaults to a client with a 10 seconds timeout.
+	Client *http.Client
+}
+
+func (o WebhookOptions) withDefaults() WebhookOptions {
+	if o.BatchSize <= 0 {
+		o.BatchSize = 100
+	}
+
+	if o.BatchInterval <= 0 {
+		o.BatchInterval = time.Second
+	}
+
+	if o.Body == nil {
+		o.Body = func(entries [][]byte) ([]byte, error) {
+			return bytes.Join(entries, nil), nil
+		}
+	}
+
+	if o.MaxRetries < 0 {
+		o.MaxRetries = 0
+	} else if o.MaxRetries == 0 {
+		o.MaxRetries = 3
+	}
+
+	if o.MinBackoff <= 0 {
+		o.MinBackoff = 500 * time.Millisecond
+	}
+
+	if o.MaxBackoff < o.MinBackoff {
+		o.MaxBackoff = max(30*time.Second, o.MinBackoff)
+	}
+
+	if o.MaxPending <= 0 {
+		o.MaxPending = 10000
+	}
+
+	if o.Client == nil {
+		o.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return o
+}
+
+// WebhookDriver POSTs batches of encoded entries to an HTTP endpoint.
+// Batches are sent in order by a single goroutine, so that a slow endpoint does not slow down logging.
+// Failed batches are reported to os.Stderr and dropped once their retries are exhausted.
+// It is safe for concurrent use.
+type WebhookDriver struct {
+	url     string
+	options WebhookOptions
+
+	lock sync.Mutex
+	//batch holds the entries written since the last batch was queued.
+	batch [][]byte
+	timer *time.Timer
+	//queue holds the batches waiting to be sent, oldest first, and pending counts their entries.
+	queue   [][][]byte
+	pending int
+	sending bool
+	//idle is signalled once the queue is empty and no batch is being sent.
+	idle    *sync.Cond
+	closed  bool
+	lastErr error
+
+	dropped atomic.Uint64
+	wake    chan struct{}
+	done    chan struct{}
+}
+
+// NewWebhookDriver returns a driver POSTing batches of entries to the url.
+//
+//	driver := drivers.NewWebhookDriver("https://collector.internal/logs", drivers.WebhookOptions{Gzip: true, BearerToken: token})
+//	logger := log.OutputDriver(driver)
+func NewWebhookDriver(url string, options WebhookOptions) *WebhookDriver {
+	w := &WebhookDriver{
+		url:     url,
+		options: options.withDefaults(),
+		wake:    make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	w.idle = sync.NewCond(&w.lock)
+
+	go w.run()
+	return w
+}
+
+// Write adds a copy of p to the current batch as one entry, queuing the batch once full.
+// An error is only returned when the driver is closed or when queuing dropped entries.
+func (w *WebhookDriver) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.closed {
+		return 0, ErrClosed
+	}
+
+	w.batch = append(w.batch, bytes.Clone(p))
+	if len(w.batch) == 1 {
+		w.timer = time.AfterFunc(w.options.BatchInterval, w.interval)
+	}
+
+	if len(w.batch) < w.options.BatchSize {
+		return len(p), nil
+	}
+
+	dropped := w.enqueue()
+	if dropped > 0 {
+		return len(p), fmt.Errorf("webhook %s is falling behind, dropped %d entries", w.url, dropped)
+	}
+
+	return len(p), nil
+}
+
+// Flush sends the current batch and blocks until every queued batch was sent or dropped.
+// It returns the error of the last batch that failed meanwhile.
+func (w *WebhookDriver) Flush() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.lastErr = nil
+	w.enqueue()
+	for len(w.queue) > 0 || w.sending {
+		w.idle.Wait()
+	}
+
+	return w.lastErr
+}
+
+// Close sends the pending batches and stops the driver once they are sent. Entries written afterwards are
+// rejected with ErrClosed. Batches failing meanwhile are not retried, so that closing does not wait for backoffs.
+func (w *WebhookDriver) Close() error {
+	Unregister(w)
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.closed {
+		return nil
+	}
+
+	w.closed = true
+	w.lastErr = nil
+	w.enqueue()
+	close(w.done)
+	for len(w.queue) > 0 || w.sending {
+		w.idle.Wait()
+	}
+
+	return w.lastErr
+}
+
+// Dropped returns the number of entries dropped because the endpoint was falling behind or failed.
+func (w *WebhookDriver) Dropped() uint64 {
+	return w.dropped.Load()
+}
+
+// interval queues the current batch once the batch interval elapsed.
+func (w *WebhookDriver) interval() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.enqueue()
+}
+
+// enqueue moves the current batch to the queue, dropping the oldest batches beyond MaxPending entries,
+// and returns the number of dropped entries. The lock must be held.
+func (w *WebhookDriver) enqueue() int {
+	if len(w.batch) == 0 {
+		return 0
+	}
+
+	w.timer.Stop()
+	w.queue = append(w.queue, w.batch)
+	w.pending += len(w.batch)
+	w.batch = nil
+
+	dropped := 0
+	for w.pending > w.options.MaxPending && len(w.queue) > 1 {
+		dropped += len(w.queue[0])
+		w.pending -= len(w.queue[0])
+		w.queue[0] = nil
+		w.queue = w.queue[1:]
+	}
+	w.dropped.Add(uint64(dropped))
+
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+
+	return dropped
+}
+
+// run sends the queued batches until the driver is closed, sending the batches queued by Close before returning.
+func (w *WebhookDriver) run() {
+	for {
+		var closed bool
+		select {
+		case <-w.done:
+			closed = true
+		case <-w.wake:
+		}
+
+		w.drain()
+		if closed {
+			return
+		}
+	}
+}
+
+// drain sends the queued batches until the queue is empty.
+func (w *WebhookDriver) drain() {
+	for {
+		w.lock.Lock()
+		if len(w.queue) == 0 {
+			w.sending = false
+			w.idle.Broadcast()
+			w.lock.Unlock()
+			return
+		}
+
+		batch := w.queue[0]
+		w.queue[0] = nil
+		w.queue = w.queue[1:]
+		w.sending = true
+		w.lock.Unlock()
+
+		err := w.send(batch)
+
+		w.lock.Lock()
+		w.pending -= len(batch)
+		if err != nil {
+			w.lastErr = err
+			w.dropped.Add(uint64(len(batch)))
+		}
+		w.lock.Unlock()
+
+		if err != nil {
+			//there is no caller to return the error to, it is reported like failed writes of the outputs
+			fmt.Fprintf(os.Stderr, "failed to send %d entries: %s\n", len(batch), err.Error())
+		}
+	}
+}
+
+// send POSTs the batch, retrying on network errors, 429 and 5xx statuses.
+func (w *WebhookDriver) send(batch [][]byte) error {
+	body, err := w.options.Body(batch)
+	if err != nil {
+		return fmt.Errorf("failed to render the request body: %w", err)
+	}
+
+	if w.options.Gzip {
+		var compressed bytes.Buffer
+		gz := gzip.NewWriter(&compressed)
+		gz.Write(body)
+		gz.Close()
+		body = compressed.Bytes()
+	}
+
+	for attempt := 0; ; attempt++ {
+		retryAfter, err := w.post(body)
+		if err == nil {
+			return nil
+		}
+
+		var permanent *permanentError
+		if errors.As(err, &permanent) || attempt >= w.options.MaxRetries {
+			return err
+		}
+
+		//a server asking for a long delay must not stall the batches behind it, nor Flush
+		delay := min(retryAfter, w.options.MaxBackoff)
+		if delay <= 0 {
+			delay = w.backoff(attempt)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-w.done:
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// permanentError is a failure that retrying would not fix, e.g. a 4xx status.
+type permanentError struct {
+	error
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.error
+}
+
+// post sends the body once. On a retryable failure, it returns the delay asked for by the Retry-After header, if any.
+func (w *WebhookDriver) post(body []byte) (time.Duration, error) {
+	req, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return 0, &permanentError{err}
+	}
+
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	for name, values := range w.options.Headers {
+		req.Header[http.CanonicalHeaderKey(name)] = values
+	}
+
+	if w.options.Gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	if len(w.options.BearerToken) > 0 {
+		req.Header.Set("Authorization", "Bearer "+w.options.BearerToken)
+	} else if len(w.options.Username) > 0 {
+		req.SetBasicAuth(w.options.Username, w.options.Password)
+	}
+
+	resp, err := w.options.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	//drain the body so that the connection is reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	switch {
+	case resp.StatusCode < 300:
+		return 0, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return retryAfter(resp.Header.Get("Retry-After")), fmt.Errorf("%s responded %s", w.url, resp.Status)
+	default:
+		return 0, &permanentError{fmt.Errorf("%s responded %s", w.url, resp.Status)}
+	}
+}
+
+// backoff returns a random delay between half MinBackoff and MinBackoff doubled attempt times, bounded by MaxBackoff,
+// so that clients failing together do not retry together.
+func (w *WebhookDriver) backoff(attempt int) time.Duration {
+	ceiling := w.options.MaxBackoff
+	if attempt < 32 {
+		ceiling = min(w.options.MinBackoff<<attempt, w.options.MaxBackoff)
+	}
+
+	return w.options.MinBackoff/2 + rand.N(ceiling-w.options.MinBackoff/2+1)
+}
+
+// retryAfter parses a Retry-After header holding either a number of seconds or an HTTP date. Zero when absent or invalid.
+func retryAfter(header string) time.Duration {
+	if len(header) == 0 {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(header); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(header); err == nil {
+		return max(time.Until(date), 0)
+	}
+
+	return 0
+}
@@ -0,0 +1,275 @@
+package drivers
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/poll"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// webhookServer records the requests it receives and answers with the queued statuses, then 204.
+type webhookServer struct {
+	*httptest.Server
+
+	lock     sync.Mutex
+	requests []*http.Request
+	bodies   []string
+	statuses []int
+	headers  []http.Header
+}
+
+func newWebhookServer(t *testing.T) *webhookServer {
+	s := &webhookServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			gz, err := gzip.NewReader(r.Body)
+			assert.Check(t, err)
+			body = gz
+		}
+		content, err := io.ReadAll(body)
+		assert.Check(t, err)
+
+		s.lock.Lock()
+		defer s.lock.Unlock()
+
+		s.requests = append(s.requests, r)
+		s.bodies = append(s.bodies, string(content))
+
+		status := http.StatusNoContent
+		if len(s.statuses) > 0 {
+			status = s.statuses[0]
+			s.statuses = s.statuses[1:]
+		}
+
+		if len(s.headers) > 0 {
+			for name, values := range s.headers[0] {
+				w.Header()[name] = values
+			}
+			s.headers = s.headers[1:]
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+// respond queues the statuses of the next responses with their headers.
+func (s *webhookServer) respond(status int, header http.Header) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.statuses = append(s.statuses, status)
+	s.headers = append(s.headers, header)
+}
+
+func (s *webhookServer) received() ([]*http.Request, []string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return append([]*http.Request(nil), s.requests...), append([]string(nil), s.bodies...)
+}
+
+func TestWebhookDriver(t *testing.T) {
+	t.Run("BATCH SIZE", func(t *testing.T) {
+		server := newWebhookServer(t)
+		driver := NewWebhookDriver(server.URL, WebhookOptions{
+			BatchSize:     2,
+			BatchInterval: time.Hour,
+			Headers:       http.Header{"X-Source": {"telemetry"}},
+			Username:      "user",
+			Password:      "secret",
+		})
+		defer driver.Close()
+
+		for _, entry := range []string{"first\n", "second\n", "third\n"} {
+			_, err := driver.Write([]byte(entry))
+			assert.NilError(t, err)
+		}
+
+		//the incomplete batch is sent by Flush
+		assert.NilError(t, driver.Flush())
+
+		requests, bodies := server.received()
+		assert.DeepEqual(t, bodies, []string{"first\nsecond\n", "third\n"})
+		assert.Equal(t, requests[0].Method, http.MethodPost)
+		assert.Equal(t, requests[0].Header.Get("Content-Type"), "text/plain; charset=utf-8")
+		assert.Equal(t, requests[0].Header.Get("X-Source"), "telemetry")
+		username, password, ok := requests[0].BasicAuth()
+		assert.Assert(t, ok)
+		assert.Equal(t, username+":"+password, "user:secret")
+	})
+
+	t.Run("BATCH INTERVAL", func(t *testing.T) {
+		server := newWebhookServer(t)
+		driver := NewWebhookDriver(server.URL, WebhookOptions{BatchInterval: 10 * time.Millisecond})
+		defer driver.Close()
+
+		_, err := driver.Write([]byte("waiting\n"))
+		assert.NilError(t, err)
+
+		poll.WaitOn(t, func(poll.LogT) poll.Result {
+			if _, bodies := server.received(); len(bodies) == 1 {
+				return poll.Success()
+			}
+
+			return poll.Continue("the batch was not sent")
+		}, poll.WithTimeout(5*time.Second), poll.WithDelay(5*time.Millisecond))
+	})
+
+	t.Run("GZIP AND BEARER TOKEN", func(t *testing.T) {
+		server := newWebhookServer(t)
+		driver := NewWebhookDriver(server.URL, WebhookOptions{Gzip: true, BearerToken: "token"})
+		defer driver.Close()
+
+		_, err := driver.Write([]byte("compressed\n"))
+		assert.NilError(t, err)
+		assert.NilError(t, driver.Flush())
+
+		requests, bodies := server.received()
+		assert.DeepEqual(t, bodies, []string{"compressed\n"})
+		assert.Equal(t, requests[0].Header.Get("Content-Encoding"), "gzip")
+		assert.Equal(t, requests[0].Header.Get("Authorization"), "Bearer token")
+	})
+
+	t.Run("CUSTOM BODY", func(t *testing.T) {
+		server := newWebhookServer(t)
+		driver := NewWebhookDriver(server.URL, WebhookOptions{
+			Headers: http.Header{"Content-Type": {"application/json"}},
+			Body: func(entries [][]byte) ([]byte, error) {
+				var text string
+				for _, e := range entries {
+					text += string(e)
+				}
+				return json.Marshal(map[string]string{"text": text})
+			},
+		})
+		defer driver.Close()
+
+		_, err := driver.Write([]byte("alert\n"))
+		assert.NilError(t, err)
+		assert.NilError(t, driver.Flush())
+
+		requests, bodies := server.received()
+		assert.DeepEqual(t, bodies, []string{`{"text":"alert\n"}`})
+		assert.Equal(t, requests[0].Header.Get("Content-Type"), "application/json")
+	})
+
+	t.Run("RETRY", func(t *testing.T) {
+		server := newWebhookServer(t)
+		server.respond(http.StatusServiceUnavailable, http.Header{"Retry-After": {"1"}})
+		server.respond(http.StatusInternalServerError, nil)
+		server.respond(http.StatusTooManyRequests, nil)
+
+		driver := NewWebhookDriver(server.URL, WebhookOptions{MinBackoff: time.Millisecond, MaxBackoff: 2 * time.Second})
+		defer driver.Close()
+
+		_, err := driver.Write([]byte("retried\n"))
+		assert.NilError(t, err)
+
+		start := time.Now()
+		assert.NilError(t, driver.Flush())
+		//the Retry-After header takes precedence over the backoff
+		assert.Assert(t, time.Since(start) >= time.Second)
+
+		_, bodies := server.received()
+		assert.DeepEqual(t, bodies, []string{"retried\n", "retried\n", "retried\n", "retried\n"})
+		assert.Equal(t, driver.Dropped(), uint64(0))
+	})
+
+	t.Run("RETRY AFTER IS CAPPED", func(t *testing.T) {
+		server := newWebhookServer(t)
+		server.respond(http.StatusServiceUnavailable, http.Header{"Retry-After": {"3600"}})
+
+		driver := NewWebhookDriver(server.URL, WebhookOptions{MinBackoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond})
+		defer driver.Close()
+
+		_, err := driver.Write([]byte("retried soon\n"))
+		assert.NilError(t, err)
+
+		start := time.Now()
+		assert.NilError(t, driver.Flush())
+		assert.Assert(t, time.Since(start) < time.Minute)
+
+		_, bodies := server.received()
+		assert.Equal(t, len(bodies), 2)
+	})
+
+	t.Run("RETRIES EXHAUSTED", func(t *testing.T) {
+		server := newWebhookServer(t)
+		for i := 0; i < 3; i++ {
+			server.respond(http.StatusBadGateway, nil)
+		}
+
+		driver := NewWebhookDriver(server.URL, WebhookOptions{MaxRetries: 2, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond})
+		defer driver.Close()
+
+		_, err := driver.Write([]byte("lost\n"))
+		assert.NilError(t, err)
+		assert.ErrorContains(t, driver.Flush(), "502 Bad Gateway")
+
+		_, bodies := server.received()
+		assert.Equal(t, len(bodies), 3)
+		assert.Equal(t, driver.Dropped(), uint64(1))
+	})
+
+	t.Run("CLIENT ERRORS ARE NOT RETRIED", func(t *testing.T) {
+		server := newWebhookServer(t)
+		server.respond(http.StatusUnauthorized, nil)
+
+		driver := NewWebhookDriver(server.URL, WebhookOptions{MinBackoff: time.Millisecond})
+		defer driver.Close()
+
+		_, err := driver.Write([]byte("rejected\n"))
+		assert.NilError(t, err)
+		assert.ErrorContains(t, driver.Flush(), "401 Unauthorized")
+
+		_, bodies := server.received()
+		assert.Equal(t, len(bodies), 1)
+	})
+
+	t.Run("CLOSED", func(t *testing.T) {
+		server := newWebhookServer(t)
+		driver := NewWebhookDriver(server.URL, WebhookOptions{})
+
+		_, err := driver.Write([]byte("sent on close\n"))
+		assert.NilError(t, err)
+		assert.NilError(t, driver.Close())
+
+		_, bodies := server.received()
+		assert.DeepEqual(t, bodies, []string{"sent on close\n"})
+
+		_, err = driver.Write([]byte("after close\n"))
+		assert.ErrorIs(t, err, ErrClosed)
+		assert.NilError(t, driver.Flush())
+		assert.NilError(t, driver.Close())
+	})
+
+	t.Run("CLOSE DOES NOT WAIT FOR RETRIES", func(t *testing.T) {
+		server := newWebhookServer(t)
+		server.respond(http.StatusServiceUnavailable, http.Header{"Retry-After": {"3600"}})
+
+		driver := NewWebhookDriver(server.URL, WebhookOptions{MinBackoff: time.Hour})
+
+		_, err := driver.Write([]byte("abandoned\n"))
+		assert.NilError(t, err)
+		assert.ErrorContains(t, driver.Close(), "503 Service Unavailable")
+		assert.Equal(t, driver.Dropped(), uint64(1))
+	})
+}
+
+func TestRetryAfter(t *testing.T) {
+	assert.Equal(t, retryAfter(""), time.Duration(0))
+	assert.Equal(t, retryAfter("120"), 2*time.Minute)
+	assert.Equal(t, retryAfter("soon"), time.Duration(0))
+
+	delay := retryAfter(time.Now().Add(time.Minute).UTC().Format(http.TimeFormat))
+	assert.Assert(t, delay > 58*time.Second && delay <= time.Minute, delay)
+}
@@ -0,0 +1,227 @@
+package encoder
+
+import (
+	"bytes"
+	"github.com/canghel3/telemetry/config"
+	"github.com/canghel3/telemetry/level"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	colorReset   = "\x1b[0m"
+	colorDim     = "\x1b[2m"
+	colorRed     = "\x1b[1;31m"
+	colorMagenta = "\x1b[1;35m"
+	colorCyan    = "\x1b[1;36m"
+)
+
+// levelWidth is the width the level column is padded to, so that the content of all built-in levels is aligned.
+const levelWidth = 5
+
+// ConsoleEncoder renders entries for humans reading a terminal:
+// colored level badges, dimmed timestamps, aligned columns and sorted metadata.
+// The entries of a transaction are rendered as a group under the transaction id.
+type ConsoleEncoder struct {
+	colors bool
+}
+
+// Console returns a ConsoleEncoder with colors enabled only when stdout is a terminal
+// and the NO_COLOR environment variable is not set.
+func Console() *ConsoleEncoder {
+	return &ConsoleEncoder{colors: colorSupported()}
+}
+
+// Colors returns a copy of the encoder with colors forcefully enabled or disabled.
+func (c *ConsoleEncoder) Colors(enabled bool) *ConsoleEncoder {
+	return &ConsoleEncoder{colors: enabled}
+}
+
+func (c *ConsoleEncoder) Encode(buf *bytes.Buffer, e *Entry, cfg config.LogConfig) {
+	if len(e.TxID) > 0 {
+		c.begin(buf, colorDim)
+		buf.WriteString("TRANSACTION ")
+		buf.WriteString(e.TxID)
+		buf.WriteString(" |")
+		c.end(buf)
+		buf.WriteByte(' ')
+	}
+
+	c.encodeLine(buf, e, cfg)
+}
+
+func (c *ConsoleEncoder) EncodeTx(buf *bytes.Buffer, id string, entries []*Entry, cfg config.LogConfig) {
+	c.paint(buf, colorDim, "┌")
+	buf.WriteByte(' ')
+	c.paint(buf, colorCyan, "TRANSACTION")
+	buf.WriteByte(' ')
+	buf.WriteString(id)
+	buf.WriteByte('\n')
+
+	for i, e := range entries {
+		prefix := "│"
+		if i == len(entries)-1 {
+			prefix = "└"
+		}
+
+		c.paint(buf, colorDim, prefix)
+		buf.WriteByte(' ')
+		c.encodeLine(buf, e, cfg)
+	}
+}
+
+func (c *ConsoleEncoder) encodeLine(buf *bytes.Buffer, e *Entry, cfg config.LogConfig) {
+	c.begin(buf, colorDim)
+	appendTimestamp(buf, e, cfg)
+	c.end(buf)
+	buf.WriteByte(' ')
+
+	levelType := e.Level.Type()
+	c.beginLevel(buf, e.Level)
+	buf.WriteString(levelType)
+	c.end(buf)
+	for i := len(levelType); i < levelWidth; i++ {
+		buf.WriteByte(' ')
+	}
+	buf.WriteByte(' ')
+
+	start := buf.Len()
+	buf.Write(e.Content)
+	sanitize(buf, start, cfg)
+
+	if len(e.Metadata) > 0 {
+		buf.WriteString("  ")
+		c.encodeMetadata(buf, e.Metadata)
+	}
+
+	if e.Error != nil {
+		buf.WriteString("  ")
+		c.paint(buf, colorRed, "error=")
+		start := buf.Len()
+		buf.WriteString(e.Error.Message)
+		quoteFrom(buf, start)
+		buf.WriteByte(' ')
+		c.paintType(buf, e.Error.Type)
+	}
+	buf.WriteByte('\n')
+
+	if e.Error != nil {
+		for _, cause := range e.Error.Causes {
+			c.paint(buf, colorDim, "      caused by: ")
+			start := buf.Len()
+			buf.WriteString(cause.Message)
+			sanitize(buf, start, cfg)
+			buf.WriteByte(' ')
+			c.paintType(buf, cause.Type)
+			buf.WriteByte('\n')
+		}
+	}
+
+	if len(e.Stack) > 0 {
+		for stack := e.Stack; len(stack) > 0; {
+			line, rest, _ := strings.Cut(stack, "\n")
+			c.begin(buf, colorDim)
+			buf.WriteString("      ")
+			buf.WriteString(line)
+			c.end(buf)
+			buf.WriteByte('\n')
+			stack = rest
+		}
+	}
+}
+
+// encodeMetadata writes the metadata as key=value pairs sorted by key.
+func (c *ConsoleEncoder) encodeMetadata(buf *bytes.Buffer, metadata map[any]any) {
+	fields := sortedFields(metadata)
+	defer releaseFields(fields)
+
+	for i, f := range *fields {
+		if i > 0 {
+			buf.WriteByte(' ')
+		}
+
+		c.begin(buf, colorDim)
+		buf.WriteString(f.key)
+		buf.WriteByte('=')
+		c.end(buf)
+
+		start := buf.Len()
+		appendValue(buf, f.value)
+		quoteFrom(buf, start)
+	}
+}
+
+func (c *ConsoleEncoder) paint(buf *bytes.Buffer, color, s string) {
+	c.begin(buf, color)
+	buf.WriteString(s)
+	c.end(buf)
+}
+
+// paintType writes the dimmed type of an error in parentheses.
+func (c *ConsoleEncoder) paintType(buf *bytes.Buffer, errorType string) {
+	c.begin(buf, colorDim)
+	buf.WriteByte('(')
+	buf.WriteString(errorType)
+	buf.WriteByte(')')
+	c.end(buf)
+}
+
+// begin starts painting what is written next with the color, until end is called.
+func (c *ConsoleEncoder) begin(buf *bytes.Buffer, color string) {
+	if c.colors {
+		buf.WriteString(color)
+	}
+}
+
+func (c *ConsoleEncoder) end(buf *bytes.Buffer) {
+	if c.colors {
+		buf.WriteString(colorReset)
+	}
+}
+
+// beginLevel starts painting with the color registered for the level, or magenta for unregistered levels.
+func (c *ConsoleEncoder) beginLevel(buf *bytes.Buffer, l level.Level) {
+	if !c.colors {
+		return
+	}
+
+	d, ok := level.Lookup(l)
+	if !ok || len(d.Color) == 0 {
+		buf.WriteString(colorMagenta)
+		return
+	}
+
+	buf.WriteString("\x1b[")
+	buf.WriteString(d.Color)
+	buf.WriteByte('m')
+}
+
+// quoteFrom quotes the text written from start when it would otherwise be ambiguous to read, or holds control characters.
+// The text is rewritten through a pooled copy, which only happens for such text.
+func quoteFrom(buf *bytes.Buffer, start int) {
+	text := buf.Bytes()[start:]
+	if len(text) > 0 && clean(text) && bytes.IndexAny(text, " \t\n\"=") < 0 {
+		return
+	}
+
+	scratch := getScratch()
+	*scratch = append((*scratch)[:0], text...)
+	buf.Truncate(start)
+	buf.Write(strconv.AppendQuote(buf.AvailableBuffer(), string(*scratch)))
+	putScratch(scratch)
+}
+
+func colorSupported() bool {
+	return len(os.Getenv("NO_COLOR")) == 0 && IsTerminal(os.Stdout)
+}
+
+// IsTerminal reports whether the file is a terminal rather than a pipe or a regular file.
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	return info.Mode()&os.ModeCharDevice != 0
+}
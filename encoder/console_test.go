@@ -0,0 +1,112 @@
+package encoder
+
+import (
+	"bytes"
+	"github.com/canghel3/telemetry/config"
+	"github.com/canghel3/telemetry/level"
+	"gotest.tools/v3/assert"
+	"os"
+	"testing"
+	"time"
+)
+
+// raceEnabled is set when testing with the race detector, under which sync.Pool randomly drops items.
+var raceEnabled bool
+
+func TestConsole(t *testing.T) {
+	cfg := config.LogConfig{Timestamp: "15:04:05"}
+	now := time.Date(2024, 3, 2, 10, 11, 12, 0, time.UTC)
+
+	t.Run("ALIGNED COLUMNS", func(t *testing.T) {
+		var buffer bytes.Buffer
+
+		c := Console().Colors(false)
+		c.Encode(&buffer, &Entry{Time: now, Level: level.Info(), Content: []byte("first")}, cfg)
+		c.Encode(&buffer, &Entry{Time: now, Level: level.Error(), Content: []byte("second")}, cfg)
+
+		assert.Equal(t, buffer.String(), "10:11:12 INFO  first\n10:11:12 ERROR second\n")
+	})
+
+	t.Run("ALLOCATIONS", func(t *testing.T) {
+		if raceEnabled {
+			t.Skip("allocations are not representative with the race detector")
+		}
+
+		e := &Entry{
+			Time:     now,
+			Level:    level.Warn(),
+			Content:  []byte("disk almost full"),
+			Metadata: map[any]any{"path": "/var/log", "free": 2048},
+			TxID:     "42",
+		}
+
+		var buffer bytes.Buffer
+		for _, c := range []*ConsoleEncoder{Console().Colors(false), Console().Colors(true)} {
+			//warm up the pools and the buffer
+			c.Encode(&buffer, e, cfg)
+
+			allocs := testing.AllocsPerRun(100, func() {
+				buffer.Reset()
+				c.Encode(&buffer, e, cfg)
+			})
+			assert.Equal(t, allocs, float64(0))
+		}
+	})
+
+	t.Run("SORTED METADATA", func(t *testing.T) {
+		var buffer bytes.Buffer
+
+		e := &Entry{
+			Time:     now,
+			Level:    level.Warn(),
+			Content:  []byte("disk almost full"),
+			Metadata: map[any]any{"path": "/var/log", "free": "2 GB", 1: true},
+		}
+		Console().Colors(false).Encode(&buffer, e, cfg)
+
+		assert.Equal(t, buffer.String(), `10:11:12 WARN  disk almost full  1=true free="2 GB" path=/var/log`+"\n")
+	})
+
+	t.Run("COLORS", func(t *testing.T) {
+		var buffer bytes.Buffer
+
+		Console().Colors(true).Encode(&buffer, &Entry{Time: now, Level: level.Error(), Content: []byte("boom")}, cfg)
+
+		assert.Equal(t, buffer.String(), colorDim+"10:11:12"+colorReset+" "+colorRed+"ERROR"+colorReset+" boom\n")
+	})
+
+	t.Run("NO_COLOR", func(t *testing.T) {
+		t.Setenv("NO_COLOR", "1")
+		assert.Equal(t, Console().colors, false)
+	})
+
+	t.Run("TRANSACTION BLOCK", func(t *testing.T) {
+		var buffer bytes.Buffer
+
+		entries := []*Entry{
+			{Time: now, Level: level.Info(), Content: []byte("begin"), TxID: "42"},
+			{Time: now, Level: level.Debug(), Content: []byte("step"), TxID: "42"},
+			{Time: now, Level: level.Custom("MAJOR"), Content: []byte("end"), TxID: "42"},
+		}
+		Console().Colors(false).EncodeTx(&buffer, "42", entries, cfg)
+
+		expected := "┌ TRANSACTION 42\n" +
+			"│ 10:11:12 INFO  begin\n" +
+			"│ 10:11:12 DEBUG step\n" +
+			"└ 10:11:12 MAJOR end\n"
+		assert.Equal(t, buffer.String(), expected)
+	})
+}
+
+func TestIsTerminal(t *testing.T) {
+	file, err := os.CreateTemp(t.TempDir(), "out")
+	assert.NilError(t, err)
+	defer file.Close()
+	assert.Assert(t, !IsTerminal(file))
+
+	reader, writer, err := os.Pipe()
+	assert.NilError(t, err)
+	defer reader.Close()
+	defer writer.Close()
+	assert.Assert(t, !IsTerminal(writer))
+}
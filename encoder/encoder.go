@@ -0,0 +1,127 @@
+package encoder
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/canghel3/telemetry/config"
+	"github.com/canghel3/telemetry/level"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTimestamp is the timestamp layout used when the configuration does not specify one.
+const DefaultTimestamp = "2006-01-02 15:04:05"
+
+// Entry is a single log entry handed to an Encoder.
+type Entry struct {
+	Time     time.Time
+	Level    level.Level
+	Metadata map[any]any
+	Content  []byte
+	// TxID is the id of the transaction the entry belongs to. Empty for entries logged outside a transaction.
+	TxID string
+	// Error is the error attached to the entry, if any.
+	Error *Error
+	// Stack is the stack trace attached to the entry, if any, in the format of FormatStack.
+	Stack string
+}
+
+// Encoder renders entries into the bytes written to an output driver.
+type Encoder interface {
+	Encode(buf *bytes.Buffer, e *Entry, cfg config.LogConfig)
+}
+
+// TxEncoder is implemented by encoders that render all entries of a transaction as a single block.
+type TxEncoder interface {
+	Encoder
+	EncodeTx(buf *bytes.Buffer, id string, entries []*Entry, cfg config.LogConfig)
+}
+
+// appendTimestamp writes the time of the entry in the configured zone and layout.
+// Invalid zones, rejected by config.Validate, fall back to the local zone.
+func appendTimestamp(buf *bytes.Buffer, e *Entry, cfg config.LogConfig) {
+	t := e.Time
+	if loc, err := config.Location(cfg.Timezone); err == nil {
+		t = t.In(loc)
+	}
+
+	buf.Write(t.AppendFormat(buf.AvailableBuffer(), timestampLayout(cfg)))
+}
+
+func timestampLayout(cfg config.LogConfig) string {
+	if len(cfg.Timestamp) > 0 {
+		return cfg.Timestamp
+	}
+
+	return DefaultTimestamp
+}
+
+// field is a metadata key rendered as a string and its value.
+type field struct {
+	key   string
+	value any
+}
+
+var fieldPool = sync.Pool{New: func() any {
+	fields := make([]field, 0, 16)
+	return &fields
+}}
+
+// sortedFields returns the metadata sorted by key, so that an entry is rendered identically every time.
+// The fields must be returned with releaseFields once written.
+func sortedFields(metadata map[any]any) *[]field {
+	fields := fieldPool.Get().(*[]field)
+	for k, v := range metadata {
+		key, ok := k.(string)
+		if !ok {
+			key = fmt.Sprint(k)
+		}
+
+		*fields = append(*fields, field{key: key, value: v})
+	}
+
+	slices.SortFunc(*fields, func(a, b field) int {
+		return strings.Compare(a.key, b.key)
+	})
+
+	return fields
+}
+
+func releaseFields(fields *[]field) {
+	clear(*fields)
+	*fields = (*fields)[:0]
+	fieldPool.Put(fields)
+}
+
+// appendValue writes the value as fmt.Sprint would, without allocating for the common types.
+func appendValue(buf *bytes.Buffer, v any) {
+	switch value := v.(type) {
+	case string:
+		buf.WriteString(value)
+	case []byte:
+		buf.Write(value)
+	case int:
+		buf.Write(strconv.AppendInt(buf.AvailableBuffer(), int64(value), 10))
+	case int64:
+		buf.Write(strconv.AppendInt(buf.AvailableBuffer(), value, 10))
+	case int32:
+		buf.Write(strconv.AppendInt(buf.AvailableBuffer(), int64(value), 10))
+	case uint:
+		buf.Write(strconv.AppendUint(buf.AvailableBuffer(), uint64(value), 10))
+	case uint64:
+		buf.Write(strconv.AppendUint(buf.AvailableBuffer(), value, 10))
+	case uint32:
+		buf.Write(strconv.AppendUint(buf.AvailableBuffer(), uint64(value), 10))
+	case float64:
+		buf.Write(strconv.AppendFloat(buf.AvailableBuffer(), value, 'g', -1, 64))
+	case bool:
+		buf.Write(strconv.AppendBool(buf.AvailableBuffer(), value))
+	case LogValuer:
+		appendValue(buf, Resolve(value))
+	default:
+		fmt.Fprint(buf, v)
+	}
+}
@@ -0,0 +1,130 @@
+package encoder
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// Error is the structured representation of an error attached to an entry.
+type Error struct {
+	Message string
+	Type    string
+	// Causes holds every error wrapped by the error, depth first,
+	// following both errors.Unwrap and errors.Join.
+	Causes []Cause
+}
+
+// Cause is a single error wrapped by an Error.
+type Cause struct {
+	Message string
+	Type    string
+}
+
+// NewError returns the structured representation of err, or nil if err is nil.
+func NewError(err error) *Error {
+	if err == nil {
+		return nil
+	}
+
+	e := &Error{
+		Message: err.Error(),
+		Type:    fmt.Sprintf("%T", err),
+	}
+
+	for _, cause := range unwrap(err) {
+		e.Causes = append(e.Causes, Cause{
+			Message: cause.Error(),
+			Type:    fmt.Sprintf("%T", cause),
+		})
+	}
+
+	return e
+}
+
+// unwrap returns every error wrapped by err, depth first.
+func unwrap(err error) []error {
+	var wrapped []error
+	switch e := err.(type) {
+	case interface{ Unwrap() error }:
+		if cause := e.Unwrap(); cause != nil {
+			wrapped = append(wrapped, cause)
+			wrapped = append(wrapped, unwrap(cause)...)
+		}
+	case interface{ Unwrap() []error }:
+		for _, cause := range e.Unwrap() {
+			if cause != nil {
+				wrapped = append(wrapped, cause)
+				wrapped = append(wrapped, unwrap(cause)...)
+			}
+		}
+	}
+
+	return wrapped
+}
+
+// ErrorStack returns the stack trace carried by err or by any error it wraps, preferring the deepest one
+// since it is the closest to where the failure originated. Errors carry a stack trace by implementing either
+// Stack() []byte or StackTrace() returning a slice of program counters, like github.com/pkg/errors does.
+// Returns an empty string when no error carries a stack trace.
+func ErrorStack(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	chain := append([]error{err}, unwrap(err)...)
+	for i := len(chain) - 1; i >= 0; i-- {
+		if stack := stackOf(chain[i]); len(stack) > 0 {
+			return stack
+		}
+	}
+
+	return ""
+}
+
+func stackOf(err error) string {
+	if s, ok := err.(interface{ Stack() []byte }); ok {
+		return strings.TrimSuffix(string(s.Stack()), "\n")
+	}
+
+	method := reflect.ValueOf(err).MethodByName("StackTrace")
+	if !method.IsValid() || method.Type().NumIn() != 0 || method.Type().NumOut() != 1 {
+		return ""
+	}
+
+	out := method.Type().Out(0)
+	if out.Kind() != reflect.Slice || out.Elem().Kind() != reflect.Uintptr {
+		return ""
+	}
+
+	trace := method.Call(nil)[0]
+	pcs := make([]uintptr, trace.Len())
+	for i := range pcs {
+		pcs[i] = uintptr(trace.Index(i).Uint())
+	}
+
+	return FormatStack(pcs)
+}
+
+// FormatStack renders the program counters in the format of runtime/debug.Stack, without the goroutine header.
+func FormatStack(pcs []uintptr) string {
+	var b strings.Builder
+
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		if len(frame.Function) > 0 {
+			if b.Len() > 0 {
+				b.WriteByte('\n')
+			}
+			fmt.Fprintf(&b, "%s\n\t%s:%d", frame.Function, frame.File, frame.Line)
+		}
+
+		if !more {
+			break
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,111 @@
+package encoder
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/canghel3/telemetry/config"
+	"github.com/canghel3/telemetry/level"
+	"gotest.tools/v3/assert"
+	"io/fs"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+type stackTrace []uintptr
+
+type tracedError struct {
+	msg   string
+	trace stackTrace
+}
+
+func newTracedError(msg string) *tracedError {
+	pcs := make([]uintptr, 16)
+	n := runtime.Callers(1, pcs)
+	return &tracedError{msg: msg, trace: pcs[:n]}
+}
+
+func (e *tracedError) Error() string {
+	return e.msg
+}
+
+func (e *tracedError) StackTrace() stackTrace {
+	return e.trace
+}
+
+type bytesStackError struct {
+	error
+}
+
+func (e bytesStackError) Stack() []byte {
+	return []byte("main.main()\n\t/app/main.go:10\n")
+}
+
+func TestNewError(t *testing.T) {
+	t.Run("NIL", func(t *testing.T) {
+		assert.Assert(t, NewError(nil) == nil)
+	})
+
+	t.Run("WRAPPED CHAIN", func(t *testing.T) {
+		_, err := os.Open("/does/not/exist")
+		wrapped := fmt.Errorf("failed to load: %w", err)
+
+		e := NewError(wrapped)
+		assert.Equal(t, e.Message, wrapped.Error())
+		assert.Equal(t, e.Type, "*fmt.wrapError")
+		assert.Equal(t, len(e.Causes), 2)
+		assert.Equal(t, e.Causes[0].Type, fmt.Sprintf("%T", &fs.PathError{}))
+		assert.Equal(t, e.Causes[1].Type, "syscall.Errno")
+	})
+
+	t.Run("JOINED", func(t *testing.T) {
+		first := errors.New("first")
+		second := fmt.Errorf("second: %w", errors.New("inner"))
+
+		e := NewError(errors.Join(first, second))
+		assert.Equal(t, len(e.Causes), 3)
+		assert.Equal(t, e.Causes[0].Message, "first")
+		assert.Equal(t, e.Causes[1].Message, "second: inner")
+		assert.Equal(t, e.Causes[2].Message, "inner")
+	})
+}
+
+func TestErrorStack(t *testing.T) {
+	t.Run("NO STACK", func(t *testing.T) {
+		assert.Equal(t, ErrorStack(errors.New("plain")), "")
+		assert.Equal(t, ErrorStack(nil), "")
+	})
+
+	t.Run("STACK TRACE METHOD", func(t *testing.T) {
+		err := fmt.Errorf("wrapped: %w", newTracedError("traced"))
+		stack := ErrorStack(err)
+		assert.Assert(t, strings.Contains(stack, "encoder.newTracedError"))
+		assert.Assert(t, strings.Contains(stack, "encoder.TestErrorStack"))
+	})
+
+	t.Run("STACK BYTES METHOD", func(t *testing.T) {
+		err := bytesStackError{errors.New("with bytes")}
+		assert.Equal(t, ErrorStack(err), "main.main()\n\t/app/main.go:10")
+	})
+}
+
+func TestTextError(t *testing.T) {
+	var buffer bytes.Buffer
+
+	e := &Entry{
+		Time:    time.Date(2024, 3, 2, 10, 11, 12, 0, time.UTC),
+		Level:   level.Error(),
+		Content: []byte("request failed"),
+		Error:   NewError(fmt.Errorf("timeout: %w", errors.New("dial tcp"))),
+		Stack:   "main.main()\n\t/app/main.go:10",
+	}
+	Text().Encode(&buffer, e, config.LogConfig{Timestamp: "15:04:05"})
+
+	expected := `10:11:12 ERROR request failed error:"timeout: dial tcp" error.type:*fmt.wrapError error.cause:"dial tcp"(*errors.errorString)` + "\n" +
+		"\tmain.main()\n" +
+		"\t\t/app/main.go:10\n"
+	assert.Equal(t, buffer.String(), expected)
+}
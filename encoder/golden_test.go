@@ -0,0 +1,129 @@
+package encoder
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/canghel3/telemetry/config"
+	"github.com/canghel3/telemetry/level"
+	"gotest.tools/v3/golden"
+	"strings"
+	"testing"
+	"time"
+)
+
+// goldenCase is a named group of entries encoded together, as a transaction when txID is set.
+type goldenCase struct {
+	name    string
+	txID    string
+	entries []*Entry
+}
+
+func goldenCorpus() []goldenCase {
+	now := time.Date(2024, 3, 2, 10, 11, 12, 345000000, time.UTC)
+	entry := func(l level.Level, content string) *Entry {
+		return &Entry{Time: now, Level: l, Content: []byte(content)}
+	}
+
+	huge := make(map[any]any, 100)
+	for i := 0; i < 100; i++ {
+		huge[fmt.Sprintf("key%03d", i)] = strings.Repeat(string(rune('a'+i%26)), i)
+	}
+
+	withMetadata := entry(level.Info(), "request served")
+	withMetadata.Metadata = map[any]any{"method": "GET", "status": 200, "path": "/orders?id=1 2", "empty": "", 3: "numeric key"}
+
+	withHuge := entry(level.Debug(), "huge metadata")
+	withHuge.Metadata = huge
+
+	withError := entry(level.Error(), "payment failed")
+	withError.Error = NewError(fmt.Errorf("charge: %w", errors.Join(errors.New("card declined"), errors.New("retry \"later\""))))
+	withError.Stack = "main.charge()\n\t/app/main.go:10\nmain.main()\n\t/app/main.go:4"
+
+	return []goldenCase{
+		{name: "levels", entries: []*Entry{
+			entry(level.Trace(), "trace"),
+			entry(level.Debug(), "debug"),
+			entry(level.Info(), "info"),
+			entry(level.Warn(), "warn"),
+			entry(level.Error(), "error"),
+			entry(level.Fatal(), "fatal"),
+			entry(level.Panic(), "panic"),
+			entry(level.Custom("AUDIT"), "custom"),
+		}},
+		{name: "unicode", entries: []*Entry{
+			entry(level.Info(), "สวัสดีชาวโลก"),
+			entry(level.Info(), "Привет, мир"),
+			entry(level.Info(), "こんにちは 🌍 naïve café"),
+		}},
+		{name: "newlines", entries: []*Entry{
+			entry(level.Info(), "first line\nsecond line\r\nthird line\n"),
+		}},
+		{name: "control characters", entries: []*Entry{
+			entry(level.Warn(), "nul\x00 bell\x07 escape\x1b[31mred\x1b[0m tab\t delete\x7f"),
+		}},
+		{name: "metadata", entries: []*Entry{withMetadata}},
+		{name: "huge metadata", entries: []*Entry{withHuge}},
+		{name: "error and stack", entries: []*Entry{withError}},
+		{name: "transaction", txID: "7b6f1c2e-9d4a-4c8e-a1f0-3e2d5c6b7a80", entries: []*Entry{
+			entry(level.Info(), "first"),
+			withMetadata,
+			entry(level.Error(), "last"),
+		}},
+	}
+}
+
+// TestGolden guards the output format of every encoder against the golden files in testdata.
+// After an intended change, rewrite them with go test ./encoder -update and review the diff.
+func TestGolden(t *testing.T) {
+	//carriage returns are part of the output under test
+	defer func(normalize bool) { golden.NormalizeCRLFToLF = normalize }(golden.NormalizeCRLFToLF)
+	golden.NormalizeCRLFToLF = false
+
+	cfg := config.LogConfig{Timestamp: "2006-01-02T15:04:05.000Z07:00", Timezone: "UTC"}
+	withMultiline := func(mode, prefix string) config.LogConfig {
+		c := cfg
+		c.Multiline, c.MultilinePrefix = mode, prefix
+		return c
+	}
+
+	encoders := map[string]struct {
+		enc Encoder
+		cfg config.LogConfig
+	}{
+		"text":           {Text(), cfg},
+		"text_escape":    {Text(), withMultiline("escape", "")},
+		"text_indent":    {Text(), withMultiline("indent", "  | ")},
+		"text_raw":       {Text(), withMultiline("raw", "")},
+		"console":        {Console().Colors(false), cfg},
+		"console_colors": {Console().Colors(true), cfg},
+	}
+
+	for name, e := range encoders {
+		t.Run(name, func(t *testing.T) {
+			var buffer bytes.Buffer
+			for _, c := range goldenCorpus() {
+				fmt.Fprintf(&buffer, "### %s\n", c.name)
+				encodeCase(&buffer, e.enc, c, e.cfg)
+			}
+
+			golden.Assert(t, buffer.String(), name+".golden")
+		})
+	}
+}
+
+// encodeCase encodes the entries of the case as a block for transaction encoders, one by one otherwise.
+func encodeCase(buf *bytes.Buffer, enc Encoder, c goldenCase, cfg config.LogConfig) {
+	if len(c.txID) > 0 {
+		if txEncoder, ok := enc.(TxEncoder); ok {
+			txEncoder.EncodeTx(buf, c.txID, c.entries, cfg)
+			return
+		}
+	}
+
+	for _, e := range c.entries {
+		tagged := *e
+		tagged.TxID = c.txID
+		enc.Encode(buf, &tagged, cfg)
+	}
+}
@@ -0,0 +1,114 @@
+package encoder
+
+import "fmt"
+
+// maxResolveDepth bounds the number of LogValuers resolved in a chain, so that a valuer returning itself cannot loop forever.
+const maxResolveDepth = 100
+
+// LogValuer is implemented by values evaluated only when the entry holding them is logged,
+// once it passed level filtering and sampling, so that costly values are not computed for dropped entries.
+// LogValuers can be used as metadata values and as Logf and Msgf arguments.
+type LogValuer interface {
+	LogValue() any
+}
+
+// Lazy adapts a function to the LogValuer interface.
+//
+//	logger.Debug().Logf("state: %s", encoder.Lazy(func() any { return dump(state) }))
+type Lazy func() any
+
+func (f Lazy) LogValue() any {
+	return f()
+}
+
+// Format formats the resolved value, so that a Lazy formatted outside the loggers renders its value rather than a pointer.
+func (f Lazy) Format(s fmt.State, verb rune) {
+	fmt.Fprintf(s, fmt.FormatString(s, verb), Resolve(f))
+}
+
+// Resolve returns the value of v, evaluating LogValuers until the value is not one.
+// A panicking LogValuer resolves to a description of the panic rather than failing the log call.
+func Resolve(v any) (resolved any) {
+	defer func() {
+		if r := recover(); r != nil {
+			resolved = fmt.Sprintf("!PANIC: %v", r)
+		}
+	}()
+
+	for i := 0; i < maxResolveDepth; i++ {
+		valuer, ok := v.(LogValuer)
+		if !ok {
+			return v
+		}
+
+		v = valuer.LogValue()
+	}
+
+	return fmt.Sprintf("!ERROR: LogValue resolved more than %d times", maxResolveDepth)
+}
+
+// ResolveMetadata returns the metadata with every LogValuer resolved.
+// The metadata is returned as is when it holds no LogValuer, it is copied otherwise.
+func ResolveMetadata(metadata map[any]any) map[any]any {
+	var resolved map[any]any
+	for k, v := range metadata {
+		if _, ok := v.(LogValuer); !ok {
+			continue
+		}
+
+		if resolved == nil {
+			resolved = make(map[any]any, len(metadata))
+			for k, v := range metadata {
+				resolved[k] = v
+			}
+		}
+
+		resolved[k] = Resolve(v)
+	}
+
+	if resolved == nil {
+		return metadata
+	}
+
+	return resolved
+}
+
+// resolveArgs returns the arguments with every LogValuer resolved.
+// The arguments are returned as is when they hold no LogValuer, they are copied otherwise.
+func resolveArgs(args []any) []any {
+	var resolved []any
+	for i, arg := range args {
+		if _, ok := arg.(LogValuer); !ok {
+			continue
+		}
+
+		if resolved == nil {
+			resolved = make([]any, len(args))
+			copy(resolved, args)
+		}
+
+		resolved[i] = Resolve(arg)
+	}
+
+	if resolved == nil {
+		return args
+	}
+
+	return resolved
+}
+
+// AppendFormat appends the arguments formatted according to the format, resolving LogValuer arguments.
+func AppendFormat(b []byte, format string, args ...any) []byte {
+	return fmt.Appendf(b, format, resolveArgs(args)...)
+}
+
+// HasLogValuer reports whether any of the values is a LogValuer.
+func HasLogValuer(values []any) bool {
+	for _, v := range values {
+		if _, ok := v.(LogValuer); ok {
+			return true
+		}
+	}
+
+	return false
+}
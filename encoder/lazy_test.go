@@ -0,0 +1,63 @@
+package encoder
+
+import (
+	"bytes"
+	"github.com/canghel3/telemetry/config"
+	"github.com/canghel3/telemetry/level"
+	"gotest.tools/v3/assert"
+	"testing"
+	"time"
+)
+
+type loop struct{}
+
+func (l loop) LogValue() any {
+	return l
+}
+
+func TestResolve(t *testing.T) {
+	assert.Equal(t, Resolve(42), 42)
+	assert.Equal(t, Resolve(Lazy(func() any { return "value" })), "value")
+	assert.Equal(t, Resolve(Lazy(func() any { return Lazy(func() any { return 1.5 }) })), 1.5)
+	assert.Equal(t, Resolve(Lazy(func() any { panic("boom") })), "!PANIC: boom")
+	assert.Equal(t, Resolve(loop{}), "!ERROR: LogValue resolved more than 100 times")
+}
+
+func TestResolveMetadata(t *testing.T) {
+	metadata := map[any]any{"plain": 1}
+	resolved := ResolveMetadata(metadata)
+	//metadata without LogValuers is not copied
+	metadata["added"] = 2
+	assert.Equal(t, len(resolved), 2)
+
+	lazy := map[any]any{"plain": 1, "lazy": Lazy(func() any { return "evaluated" })}
+	resolved = ResolveMetadata(lazy)
+	assert.DeepEqual(t, resolved, map[any]any{"plain": 1, "lazy": "evaluated"})
+	_, ok := lazy["lazy"].(Lazy)
+	assert.Assert(t, ok, "the metadata must not be modified")
+}
+
+func TestAppendFormat(t *testing.T) {
+	args := []any{Lazy(func() any { return "lazy" }), 42}
+	assert.Equal(t, string(AppendFormat(nil, "%s %d", args...)), "lazy 42")
+	_, ok := args[0].(Lazy)
+	assert.Assert(t, ok, "the arguments must not be modified")
+}
+
+func TestEncodeLazy(t *testing.T) {
+	e := &Entry{
+		Time:     time.Date(2024, 3, 2, 10, 0, 0, 0, time.UTC),
+		Level:    level.Info(),
+		Metadata: map[any]any{"state": Lazy(func() any { return "ready" })},
+		Content:  []byte("message"),
+	}
+	cfg := config.LogConfig{Timestamp: time.RFC3339, Timezone: "UTC"}
+
+	var buffer bytes.Buffer
+	Text().Encode(&buffer, e, cfg)
+	assert.Equal(t, buffer.String(), "2024-03-02T10:00:00Z INFO state:ready message\n")
+
+	buffer.Reset()
+	Console().Colors(false).Encode(&buffer, e, cfg)
+	assert.Assert(t, bytes.Contains(buffer.Bytes(), []byte("ready")), buffer.String())
+}
@@ -0,0 +1,118 @@
+package encoder
+
+import (
+	"bytes"
+	"github.com/canghel3/telemetry/config"
+	"sync"
+)
+
+const hex = "0123456789abcdef"
+
+// defaultMultilinePrefix prefixes continuation lines in the indent mode when no prefix is configured.
+const defaultMultilinePrefix = "\t"
+
+// multilinePrefix returns the prefix of continuation lines of the configuration.
+func multilinePrefix(cfg config.LogConfig) string {
+	if len(cfg.MultilinePrefix) > 0 {
+		return cfg.MultilinePrefix
+	}
+
+	return defaultMultilinePrefix
+}
+
+// raw reports whether the configuration writes text as is, which is the default.
+func raw(cfg config.LogConfig) bool {
+	return len(cfg.Multiline) == 0 || cfg.Multiline == "raw"
+}
+
+// sanitize applies the multiline policy of the configuration to the bytes written to the buffer from start:
+// line feeds are escaped or followed by the continuation prefix, and other control characters are escaped,
+// so that the written text can neither break line oriented parsing nor inject terminal sequences.
+// The raw mode leaves the text as is.
+func sanitize(buf *bytes.Buffer, start int, cfg config.LogConfig) {
+	if raw(cfg) || clean(buf.Bytes()[start:]) {
+		return
+	}
+
+	//the text is rewritten in place through a pooled copy, which only happens for text holding control characters
+	written := getScratch()
+	*written = append((*written)[:0], buf.Bytes()[start:]...)
+	buf.Truncate(start)
+	appendSanitized(buf, *written, cfg)
+	putScratch(written)
+}
+
+// clean reports whether the text holds no character rewritten by sanitize.
+func clean[T string | []byte](text T) bool {
+	for i := 0; i < len(text); i++ {
+		if control(text[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func appendSanitized(buf *bytes.Buffer, text []byte, cfg config.LogConfig) {
+	indent := cfg.Multiline != "escape"
+	prefix := multilinePrefix(cfg)
+	if indent {
+		//trailing line feeds would leave lines holding nothing but the prefix
+		for bytes.HasSuffix(text, []byte("\n")) {
+			text = bytes.TrimSuffix(bytes.TrimSuffix(text, []byte("\n")), []byte("\r"))
+		}
+	}
+
+	for len(text) > 0 {
+		c := text[0]
+		if !control(c) {
+			//copy the run of characters written as is at once
+			n := 1
+			for n < len(text) && !control(text[n]) {
+				n++
+			}
+			buf.Write(text[:n])
+			text = text[n:]
+			continue
+		}
+
+		switch {
+		case c == '\n' && indent:
+			buf.WriteByte('\n')
+			buf.WriteString(prefix)
+		case c == '\n':
+			buf.WriteString(`\n`)
+		case c == '\r' && indent && len(text) > 1 && text[1] == '\n':
+			//a CRLF line break is indented like a line feed
+		case c == '\r':
+			buf.WriteString(`\r`)
+		default:
+			buf.WriteString(`\x`)
+			buf.WriteByte(hex[c>>4])
+			buf.WriteByte(hex[c&0xf])
+		}
+		text = text[1:]
+	}
+}
+
+// control reports whether the byte is an ASCII control character other than the tab.
+func control(c byte) bool {
+	return (c < ' ' && c != '\t') || c == 0x7f
+}
+
+var scratches = sync.Pool{New: func() any {
+	return new([]byte)
+}}
+
+func getScratch() *[]byte {
+	return scratches.Get().(*[]byte)
+}
+
+// putScratch returns the scratch to the pool, unless it grew too large to be worth keeping.
+func putScratch(scratch *[]byte) {
+	if cap(*scratch) > 64<<10 {
+		return
+	}
+
+	scratches.Put(scratch)
+}
@@ -0,0 +1,7 @@
+//go:build race
+
+package encoder
+
+func init() {
+	raceEnabled = true
+}
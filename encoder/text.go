@@ -0,0 +1,85 @@
+package encoder
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/canghel3/telemetry/config"
+	"strings"
+)
+
+// TextEncoder renders every entry on a single line:
+// TIMESTAMP [TRANSACTION ID |] LEVEL METADATA CONTENT [ERROR], the metadata being sorted by key,
+// followed by the stack trace, if any, indented on the next lines.
+// Line feeds and control characters are written according to config.LogConfig.Multiline.
+type TextEncoder struct {
+}
+
+func Text() *TextEncoder {
+	return &TextEncoder{}
+}
+
+func (t *TextEncoder) Encode(buf *bytes.Buffer, e *Entry, cfg config.LogConfig) {
+	// format timestamp
+	appendTimestamp(buf, e, cfg)
+	buf.WriteByte(' ')
+
+	//transaction log prefix
+	if len(e.TxID) > 0 {
+		buf.WriteString("TRANSACTION ")
+		buf.WriteString(e.TxID)
+		buf.WriteString(" | ")
+	}
+
+	// format level
+	buf.WriteString(e.Level.Type())
+	buf.WriteByte(' ')
+
+	// format metadata, sorted by key
+	if len(e.Metadata) > 0 {
+		fields := sortedFields(e.Metadata)
+		for _, f := range *fields {
+			start := buf.Len()
+			buf.WriteString(f.key)
+			buf.WriteByte(':')
+			appendValue(buf, f.value)
+			sanitize(buf, start, cfg)
+			buf.WriteByte(' ')
+		}
+		releaseFields(fields)
+	}
+
+	// add content
+	start := buf.Len()
+	buf.Write(e.Content)
+	sanitize(buf, start, cfg)
+
+	// format error
+	if e.Error != nil {
+		fmt.Fprintf(buf, " error:%q error.type:%s", e.Error.Message, e.Error.Type)
+		for _, cause := range e.Error.Causes {
+			fmt.Fprintf(buf, " error.cause:%q(%s)", cause.Message, cause.Type)
+		}
+	}
+
+	// format stack trace, on the entry line when line feeds are escaped
+	if len(e.Stack) > 0 && cfg.Multiline == "escape" {
+		buf.WriteString(" stack:")
+		start := buf.Len()
+		buf.WriteString(e.Stack)
+		sanitize(buf, start, cfg)
+	}
+	buf.WriteByte('\n')
+
+	if len(e.Stack) > 0 && cfg.Multiline != "escape" {
+		writeIndented(buf, e.Stack, multilinePrefix(cfg))
+	}
+}
+
+// writeIndented writes every line of s prefixed with indent.
+func writeIndented(buf *bytes.Buffer, s, indent string) {
+	for _, line := range strings.Split(s, "\n") {
+		buf.WriteString(indent)
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+}
@@ -0,0 +1,128 @@
+// Package enrich resolves the metadata describing the process and host added to every entry.
+package enrich
+
+import (
+	"github.com/canghel3/telemetry/config"
+	"os"
+	"path"
+	"runtime/debug"
+	"strings"
+)
+
+// Metadata keys of the enrichers.
+const (
+	Hostname    = "hostname"
+	PID         = "pid"
+	Service     = "service"
+	Version     = "version"
+	Environment = "environment"
+	Pod         = "k8s.pod"
+	Namespace   = "k8s.namespace"
+)
+
+// kubernetes is the enricher adding the Pod and Namespace keys.
+const kubernetes = "kubernetes"
+
+// namespaceFile holds the namespace of the pod, mounted by Kubernetes with the service account token.
+var namespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// Metadata returns the metadata of the enrichers switched on in the configuration.
+// Values that cannot be determined, e.g. the pod outside of Kubernetes, are omitted.
+// The values do not change during the lifetime of the process, callers should resolve them once per configuration.
+func Metadata(cfg config.EnrichConfig) map[any]any {
+	if len(cfg.Fields) == 0 {
+		return nil
+	}
+
+	meta := make(map[any]any)
+	set := func(key, value string) {
+		if len(value) > 0 {
+			meta[key] = value
+		}
+	}
+
+	for _, field := range cfg.Fields {
+		switch field {
+		case Hostname:
+			hostname, _ := os.Hostname()
+			set(Hostname, hostname)
+		case PID:
+			meta[PID] = os.Getpid()
+		case Service:
+			set(Service, service(cfg))
+		case Version:
+			set(Version, version())
+		case Environment:
+			set(Environment, first(cfg.Environment, os.Getenv("ENVIRONMENT")))
+		case kubernetes:
+			//the service host is set in every container started by Kubernetes
+			if len(os.Getenv("KUBERNETES_SERVICE_HOST")) == 0 {
+				continue
+			}
+
+			hostname, _ := os.Hostname()
+			set(Pod, first(os.Getenv("POD_NAME"), hostname))
+
+			namespace, _ := os.ReadFile(namespaceFile)
+			set(Namespace, first(os.Getenv("POD_NAMESPACE"), strings.TrimSpace(string(namespace))))
+		}
+	}
+
+	return meta
+}
+
+func service(cfg config.EnrichConfig) string {
+	name := first(cfg.Service, os.Getenv("OTEL_SERVICE_NAME"), os.Getenv("SERVICE_NAME"))
+	if len(name) > 0 {
+		return name
+	}
+
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+
+	return path.Base(first(info.Main.Path, info.Path))
+}
+
+// version returns the version of the main module, or the revision it was built from for development builds.
+func version() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+
+	if len(info.Main.Version) > 0 && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+
+	var revision, modified string
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.modified":
+			modified = setting.Value
+		}
+	}
+
+	if len(revision) > 12 {
+		revision = revision[:12]
+	}
+
+	if len(revision) > 0 && modified == "true" {
+		revision += "-dirty"
+	}
+
+	return revision
+}
+
+func first(values ...string) string {
+	for _, v := range values {
+		if len(v) > 0 {
+			return v
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,59 @@
+package enrich
+
+import (
+	"github.com/canghel3/telemetry/config"
+	"gotest.tools/v3/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMetadata(t *testing.T) {
+	t.Run("NONE", func(t *testing.T) {
+		assert.Assert(t, Metadata(config.EnrichConfig{Service: "payments"}) == nil)
+	})
+
+	t.Run("PROCESS", func(t *testing.T) {
+		t.Setenv("ENVIRONMENT", "staging")
+		t.Setenv("SERVICE_NAME", "")
+		t.Setenv("OTEL_SERVICE_NAME", "")
+
+		hostname, err := os.Hostname()
+		assert.NilError(t, err)
+
+		meta := Metadata(config.EnrichConfig{Fields: []string{"hostname", "pid", "service", "environment"}})
+		assert.DeepEqual(t, meta, map[any]any{
+			Hostname:    hostname,
+			PID:         os.Getpid(),
+			Service:     "telemetry",
+			Environment: "staging",
+		})
+	})
+
+	t.Run("CONFIGURED VALUES", func(t *testing.T) {
+		t.Setenv("ENVIRONMENT", "staging")
+		t.Setenv("SERVICE_NAME", "billing")
+
+		meta := Metadata(config.EnrichConfig{Fields: []string{"service", "environment"}})
+		assert.DeepEqual(t, meta, map[any]any{Service: "billing", Environment: "staging"})
+
+		meta = Metadata(config.EnrichConfig{Fields: []string{"service", "environment"}, Service: "payments", Environment: "production"})
+		assert.DeepEqual(t, meta, map[any]any{Service: "payments", Environment: "production"})
+	})
+
+	t.Run("KUBERNETES", func(t *testing.T) {
+		cfg := config.EnrichConfig{Fields: []string{"kubernetes"}}
+
+		t.Setenv("KUBERNETES_SERVICE_HOST", "")
+		assert.DeepEqual(t, Metadata(cfg), map[any]any{})
+
+		defer func(name string) { namespaceFile = name }(namespaceFile)
+		namespaceFile = filepath.Join(t.TempDir(), "namespace")
+		assert.NilError(t, os.WriteFile(namespaceFile, []byte("payments\n"), 0600))
+
+		t.Setenv("KUBERNETES_SERVICE_HOST", "10.0.0.1")
+		t.Setenv("POD_NAME", "api-7d9f")
+		t.Setenv("POD_NAMESPACE", "")
+		assert.DeepEqual(t, Metadata(cfg), map[any]any{Pod: "api-7d9f", Namespace: "payments"})
+	})
+}
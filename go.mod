@@ -3,13 +3,14 @@ module github.com/canghel3/telemetry
 go 1.22.3
 
 require (
+	github.com/fsnotify/fsnotify v1.7.0
+	github.com/golang/snappy v1.0.0
 	github.com/google/uuid v1.6.0
 	github.com/spf13/viper v1.19.0
 	gotest.tools/v3 v3.5.1
 )
 
 require (
-	github.com/fsnotify/fsnotify v1.7.0 // indirect
 	github.com/google/go-cmp v0.5.9 // indirect
 	github.com/hashicorp/hcl v1.0.0 // indirect
 	github.com/magiconair/properties v1.8.7 // indirect
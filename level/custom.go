@@ -4,7 +4,15 @@ type CustomLevel struct {
 	levelType string
 }
 
+// Custom returns the registered custom level with the given name, or a new unregistered one.
+// Register a custom level to give it a severity, aliases and a color.
 func Custom(_type string) *CustomLevel {
+	if d, ok := Lookup(&CustomLevel{levelType: _type}); ok {
+		if c, ok := d.Level.(*CustomLevel); ok && c.levelType == _type {
+			return c
+		}
+	}
+
 	return &CustomLevel{levelType: _type}
 }
 
@@ -4,8 +4,10 @@ type LevelDebug struct {
 	levelType string
 }
 
+var debugLevel = &LevelDebug{levelType: "DEBUG"}
+
 func Debug() *LevelDebug {
-	return &LevelDebug{levelType: "DEBUG"}
+	return debugLevel
 }
 
 func (ld *LevelDebug) Type() string {
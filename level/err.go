@@ -4,8 +4,10 @@ type LevelError struct {
 	levelType string
 }
 
+var errorLevel = &LevelError{levelType: "ERROR"}
+
 func Error() *LevelError {
-	return &LevelError{levelType: "ERROR"}
+	return errorLevel
 }
 
 func (le *LevelError) Type() string {
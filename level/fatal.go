@@ -0,0 +1,15 @@
+package level
+
+type LevelFatal struct {
+	levelType string
+}
+
+var fatalLevel = &LevelFatal{levelType: "FATAL"}
+
+func Fatal() *LevelFatal {
+	return fatalLevel
+}
+
+func (lf *LevelFatal) Type() string {
+	return lf.levelType
+}
@@ -4,8 +4,10 @@ type LevelInfo struct {
 	levelType string
 }
 
+var infoLevel = &LevelInfo{levelType: "INFO"}
+
 func Info() *LevelInfo {
-	return &LevelInfo{levelType: "INFO"}
+	return infoLevel
 }
 
 func (li *LevelInfo) Type() string {
@@ -1,5 +1,7 @@
 package level
 
+// Level is the level of a message. The constructors of the built-in levels,
+// such as Info, always return the same instance, so that they never allocate.
 type Level interface {
 	Type() string
 }
@@ -0,0 +1,15 @@
+package level
+
+type LevelPanic struct {
+	levelType string
+}
+
+var panicLevel = &LevelPanic{levelType: "PANIC"}
+
+func Panic() *LevelPanic {
+	return panicLevel
+}
+
+func (lp *LevelPanic) Type() string {
+	return lp.levelType
+}
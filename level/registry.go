@@ -0,0 +1,147 @@
+package level
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Severities of the built-in levels. Custom levels can use any value in between.
+const (
+	SeverityTrace = -8
+	SeverityDebug = -4
+	SeverityInfo  = 0
+	SeverityWarn  = 4
+	SeverityError = 8
+	SeverityPanic = 12
+	SeverityFatal = 16
+)
+
+// Definition describes a registered level.
+type Definition struct {
+	Level    Level
+	Severity int
+	// Aliases are alternative names accepted by Parse, e.g. "warning" for WARN.
+	Aliases []string
+	// Color is the ANSI SGR parameter used to render the level on a terminal, e.g. "1;31" for bold red.
+	Color string
+}
+
+var registry = struct {
+	lock sync.RWMutex
+	//definitions are indexed by the lower case name and aliases of the level
+	definitions map[string]*Definition
+	//types indexes the definitions by the exact name of the level, so that looking up a level does not allocate
+	types map[string]*Definition
+}{definitions: make(map[string]*Definition), types: make(map[string]*Definition)}
+
+func init() {
+	builtins := []Definition{
+		{Level: Trace(), Severity: SeverityTrace, Color: "1;37"},
+		{Level: Debug(), Severity: SeverityDebug, Color: "1;34"},
+		{Level: Info(), Severity: SeverityInfo, Aliases: []string{"information"}, Color: "1;32"},
+		{Level: Warn(), Severity: SeverityWarn, Aliases: []string{"warning"}, Color: "1;33"},
+		{Level: Error(), Severity: SeverityError, Aliases: []string{"err"}, Color: "1;31"},
+		{Level: Panic(), Severity: SeverityPanic, Color: "1;35"},
+		{Level: Fatal(), Severity: SeverityFatal, Aliases: []string{"critical"}, Color: "1;41"},
+	}
+
+	for _, d := range builtins {
+		err := Register(d)
+		if err != nil {
+			panic(err)
+		}
+	}
+}
+
+// Register makes the level known to Parse, Lookup and Severity.
+// The level name and aliases are case-insensitive and must not be registered already.
+func Register(d Definition) error {
+	if d.Level == nil || len(d.Level.Type()) == 0 {
+		return fmt.Errorf("level must have a name")
+	}
+
+	names := append([]string{d.Level.Type()}, d.Aliases...)
+
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+
+	for _, name := range names {
+		if _, ok := registry.definitions[strings.ToLower(name)]; ok {
+			return fmt.Errorf("level %q is already registered", name)
+		}
+	}
+
+	definition := d
+	for _, name := range names {
+		registry.definitions[strings.ToLower(name)] = &definition
+	}
+	registry.types[d.Level.Type()] = &definition
+
+	return nil
+}
+
+// Parse returns the registered level with the given name or alias, ignoring case.
+func Parse(s string) (Level, error) {
+	registry.lock.RLock()
+	defer registry.lock.RUnlock()
+
+	d, ok := registry.definitions[strings.ToLower(strings.TrimSpace(s))]
+	if !ok {
+		return nil, fmt.Errorf("unknown level %q, expected one of %s", s, strings.Join(names(), ", "))
+	}
+
+	return d.Level, nil
+}
+
+// Lookup returns the definition registered under the exact name of the level.
+// Unlike Parse, it ignores aliases and case, so that Custom("warning") is not mistaken for WARN.
+func Lookup(l Level) (Definition, bool) {
+	registry.lock.RLock()
+	defer registry.lock.RUnlock()
+
+	d, ok := registry.types[l.Type()]
+	if !ok {
+		return Definition{}, false
+	}
+
+	return *d, true
+}
+
+// Severity returns the severity of the level. Unregistered levels have the severity of INFO,
+// although outputs never filter them out by their minimum level.
+func Severity(l Level) int {
+	d, ok := Lookup(l)
+	if !ok {
+		return SeverityInfo
+	}
+
+	return d.Severity
+}
+
+// names returns the names of the registered levels ordered by severity. The caller must hold the registry lock.
+func names() []string {
+	var definitions []*Definition
+	seen := make(map[*Definition]bool)
+	for _, d := range registry.definitions {
+		if !seen[d] {
+			seen[d] = true
+			definitions = append(definitions, d)
+		}
+	}
+
+	sort.Slice(definitions, func(i, j int) bool {
+		if definitions[i].Severity == definitions[j].Severity {
+			return definitions[i].Level.Type() < definitions[j].Level.Type()
+		}
+		return definitions[i].Severity < definitions[j].Severity
+	})
+
+	n := make([]string, len(definitions))
+	for i, d := range definitions {
+		n[i] = strings.ToLower(d.Level.Type())
+	}
+
+	return n
+}
@@ -0,0 +1,100 @@
+package level
+
+import (
+	"gotest.tools/v3/assert"
+	"strings"
+	"testing"
+)
+
+// unregister removes the levels registered by a test, so that the registry is the same for every run.
+func unregister(levels ...Level) {
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+
+	for _, l := range levels {
+		d, ok := registry.types[l.Type()]
+		if !ok {
+			continue
+		}
+
+		for _, name := range append([]string{l.Type()}, d.Aliases...) {
+			delete(registry.definitions, strings.ToLower(name))
+		}
+		delete(registry.types, l.Type())
+	}
+}
+
+func TestParse(t *testing.T) {
+	t.Run("BUILT-IN LEVELS", func(t *testing.T) {
+		for input, expected := range map[string]string{
+			"trace":   "TRACE",
+			"DEBUG":   "DEBUG",
+			" Info ":  "INFO",
+			"warn":    "WARN",
+			"warning": "WARN",
+			"err":     "ERROR",
+			"panic":   "PANIC",
+			"fatal":   "FATAL",
+		} {
+			l, err := Parse(input)
+			assert.NilError(t, err)
+			assert.Equal(t, l.Type(), expected)
+		}
+	})
+
+	t.Run("PARSED LEVELS KEEP THEIR TYPE", func(t *testing.T) {
+		l, err := Parse("fatal")
+		assert.NilError(t, err)
+		_, ok := l.(*LevelFatal)
+		assert.Assert(t, ok)
+	})
+
+	t.Run("UNKNOWN LEVEL", func(t *testing.T) {
+		_, err := Parse("verbose")
+		assert.ErrorContains(t, err, `unknown level "verbose", expected one of `)
+		//levels registered by other tests may be listed as well
+		for _, name := range []string{"trace", "debug", "info", "warn", "error", "panic", "fatal"} {
+			assert.Assert(t, strings.Contains(err.Error(), name), err.Error())
+		}
+		assert.Assert(t, strings.Index(err.Error(), "trace") < strings.Index(err.Error(), "fatal"))
+	})
+}
+
+func TestRegister(t *testing.T) {
+	t.Run("CUSTOM LEVEL", func(t *testing.T) {
+		major := Custom("MAJOR_TEST")
+		t.Cleanup(func() { unregister(major) })
+		err := Register(Definition{Level: major, Severity: SeverityWarn + 1, Aliases: []string{"big"}, Color: "1;36"})
+		assert.NilError(t, err)
+
+		parsed, err := Parse("big")
+		assert.NilError(t, err)
+		assert.Equal(t, parsed, Level(major))
+		assert.Equal(t, Custom("MAJOR_TEST"), major)
+		assert.Equal(t, Severity(major), SeverityWarn+1)
+
+		d, ok := Lookup(major)
+		assert.Assert(t, ok)
+		assert.Equal(t, d.Color, "1;36")
+	})
+
+	t.Run("DUPLICATE NAME OR ALIAS", func(t *testing.T) {
+		assert.ErrorContains(t, Register(Definition{Level: Custom("info")}), `level "info" is already registered`)
+		assert.ErrorContains(t, Register(Definition{Level: Custom("NOTICE"), Aliases: []string{"warning"}}), `level "warning" is already registered`)
+
+		_, err := Parse("notice")
+		assert.ErrorContains(t, err, "unknown level")
+	})
+
+	t.Run("UNREGISTERED SEVERITY", func(t *testing.T) {
+		assert.Equal(t, Severity(Custom("UNREGISTERED")), SeverityInfo)
+		//aliases and other cases only apply to Parse, custom levels are not mistaken for built-in ones
+		_, ok := Lookup(Custom("warning"))
+		assert.Assert(t, !ok)
+		_, ok = Lookup(Custom("info"))
+		assert.Assert(t, !ok)
+		assert.Equal(t, Severity(Custom("warning")), SeverityInfo)
+		assert.Assert(t, Severity(Trace()) < Severity(Debug()))
+		assert.Assert(t, Severity(Error()) < Severity(Fatal()))
+	})
+}
@@ -0,0 +1,15 @@
+package level
+
+type LevelTrace struct {
+	levelType string
+}
+
+var traceLevel = &LevelTrace{levelType: "TRACE"}
+
+func Trace() *LevelTrace {
+	return traceLevel
+}
+
+func (lt *LevelTrace) Type() string {
+	return lt.levelType
+}
@@ -4,8 +4,10 @@ type LevelWarn struct {
 	levelType string
 }
 
+var warnLevel = &LevelWarn{levelType: "WARN"}
+
 func Warn() *LevelWarn {
-	return &LevelWarn{levelType: "WARN"}
+	return warnLevel
 }
 
 func (wl *LevelWarn) Type() string {
@@ -0,0 +1,133 @@
+package log
+
+import (
+	"github.com/canghel3/telemetry/config"
+	"github.com/canghel3/telemetry/level"
+	"gotest.tools/v3/assert"
+	"io"
+	"testing"
+)
+
+// discardOutput returns an output writing to io.Discard with the minimum level set to INFO.
+func discardOutput() *Output {
+	cfg := config.Default()
+	cfg.Level = "info"
+	return New(io.Discard, cfg)
+}
+
+// raceEnabled is set when testing with the race detector, under which sync.Pool randomly drops items.
+var raceEnabled bool
+
+func TestAllocations(t *testing.T) {
+	if raceEnabled {
+		t.Skip("allocations are not representative with the race detector")
+	}
+
+	o := discardOutput()
+	withMetadata := discardOutput().Metadata(map[any]any{"service": "payments"})
+
+	//warm up the pools and the compiled configuration
+	o.Info().Log("warm up")
+	withMetadata.Info().Log("warm up")
+
+	t.Run("DISABLED", func(t *testing.T) {
+		allocs := testing.AllocsPerRun(100, func() {
+			o.Debug().Log("disabled")
+			o.Debug().Logf("disabled %d", 42)
+		})
+		assert.Equal(t, allocs, float64(0))
+	})
+
+	t.Run("ENABLED", func(t *testing.T) {
+		allocs := testing.AllocsPerRun(100, func() {
+			o.Info().Log("enabled")
+		})
+		assert.Equal(t, allocs, float64(0))
+	})
+
+	t.Run("ENABLED WITH METADATA", func(t *testing.T) {
+		allocs := testing.AllocsPerRun(100, func() {
+			withMetadata.Info().Log("enabled")
+		})
+		assert.Equal(t, allocs, float64(0))
+	})
+}
+
+func BenchmarkDisabled(b *testing.B) {
+	o := discardOutput()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		o.Debug().Log("disabled")
+	}
+}
+
+func BenchmarkDisabledf(b *testing.B) {
+	o := discardOutput()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		o.Debug().Logf("disabled %d", i)
+	}
+}
+
+func BenchmarkEnabled(b *testing.B) {
+	o := discardOutput()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		o.Info().Log("enabled")
+	}
+}
+
+func BenchmarkEnabledf(b *testing.B) {
+	o := discardOutput()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		o.Info().Logf("request %s served in %dms", "/orders", 42)
+	}
+}
+
+func BenchmarkEnabledMetadata(b *testing.B) {
+	o := discardOutput().Metadata(map[any]any{"service": "payments", "region": "eu", "attempt": 3})
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		o.Info().Log("enabled")
+	}
+}
+
+func BenchmarkEnabledParallel(b *testing.B) {
+	o := discardOutput()
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			o.Info().Log("enabled")
+		}
+	})
+}
+
+func BenchmarkNamed(b *testing.B) {
+	defer unregister("bench", "bench.child")
+	Named("bench").Driver(io.Discard).Metadata(map[any]any{"service": "payments"}).SetLevel(level.Info())
+	o := Named("bench.child")
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		o.Info().Log("enabled")
+	}
+}
+
+func BenchmarkTransaction(b *testing.B) {
+	o := discardOutput()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		tx := BeginTx()
+		tx.Append(o.Info().Msg("first"))
+		tx.Append(o.Info().Msg("second"))
+		tx.Log()
+	}
+}
@@ -0,0 +1,28 @@
+package log
+
+import (
+	"bytes"
+	"sync"
+)
+
+// maxPooledBuffer is the capacity above which a buffer is left to the garbage collector instead of being reused.
+const maxPooledBuffer = 64 << 10
+
+var buffers = sync.Pool{New: func() any {
+	return new(bytes.Buffer)
+}}
+
+// getBuffer returns an empty buffer from the pool. Drivers must not retain the bytes written to them,
+// as required by io.Writer, since the buffer is reused once returned with putBuffer.
+func getBuffer() *bytes.Buffer {
+	return buffers.Get().(*bytes.Buffer)
+}
+
+func putBuffer(buffer *bytes.Buffer) {
+	if buffer.Cap() > maxPooledBuffer {
+		return
+	}
+
+	buffer.Reset()
+	buffers.Put(buffer)
+}
@@ -0,0 +1,53 @@
+package log
+
+import "time"
+
+// Clock tells the time of the entries of an Output or a Tx.
+// Clocks should return times carrying a monotonic reading, as time.Now does,
+// so that durations measured between entries are not affected by wall clock changes.
+// The zone timestamps are rendered in is selected with the timezone setting of the configuration rather than by the clock,
+// since converting a time to another zone strips its monotonic reading.
+type Clock interface {
+	Now() time.Time
+}
+
+// ClockFunc adapts a function to the Clock interface.
+type ClockFunc func() time.Time
+
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+// SystemClock is the Clock used when none is set, reading the system time.
+var SystemClock Clock = ClockFunc(time.Now)
+
+// Clock sets the clock telling the time of the entries of the output. Nil restores the SystemClock.
+// The clock also measures the rate limits and duplicate windows of the output and the expiry of SetLevelFor,
+// while the timers ending duplicate bursts and temporary levels keep waiting for real durations.
+// Named loggers without a clock use the clock of their parent.
+func (o *Output) Clock(c Clock) *Output {
+	o.clock = c
+	return o
+}
+
+// now returns the time of the clock of the output, inherited from the parent for named loggers.
+func (o *Output) now() time.Time {
+	if o.clock != nil {
+		return o.clock.Now()
+	}
+
+	if o.parent != nil {
+		return o.parent.now()
+	}
+
+	return SystemClock.Now()
+}
+
+// Clock sets the clock telling the time of the entries of the transaction, overriding the clocks of their outputs.
+func (tx *Tx) Clock(c Clock) *Tx {
+	tx.lock.Lock()
+	defer tx.lock.Unlock()
+
+	tx.clock = c
+	return tx
+}
@@ -0,0 +1,80 @@
+package log
+
+import (
+	"fmt"
+	"github.com/canghel3/telemetry/drivers"
+	"github.com/canghel3/telemetry/level"
+	"io"
+	"os"
+	"sync"
+)
+
+// ExitFunc is called with exit code 1 after a message with the fatal level is logged.
+// It can be replaced, e.g. by tests, to prevent the process from terminating.
+var ExitFunc = os.Exit
+
+// PanicFunc is called with the message content after a message with the panic level is logged.
+// It can be replaced, e.g. by tests, to intercept the panic.
+var PanicFunc = func(msg string) {
+	panic(msg)
+}
+
+// pending holds the transactions that opted in to FlushOnExit and were neither logged nor discarded.
+var pending sync.Map
+
+// Flush logs every pending transaction that opted in to FlushOnExit and flushes every registered output driver
+// that buffers entries. It is called before terminating the process on fatal and panic levels,
+// and should be called by applications before exiting on their own.
+func Flush() {
+	pending.Range(func(key, _ any) bool {
+		key.(*Tx).Log()
+		return true
+	})
+
+	for _, flusher := range drivers.Registered() {
+		flush(flusher)
+	}
+}
+
+// register keeps track of the driver if it buffers entries, until the driver unregisters itself.
+func register(driver io.Writer) {
+	if flusher, ok := driver.(drivers.Flusher); ok {
+		drivers.Register(flusher)
+	}
+}
+
+func flush(flusher drivers.Flusher) {
+	err := flusher.Flush()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to flush driver: %s\n", err.Error())
+	}
+}
+
+// terminal reports whether logging a message with the level ends the process or panics.
+func terminal(l level.Level) bool {
+	switch l.(type) {
+	case *level.LevelFatal, *level.LevelPanic:
+		return true
+	default:
+		return false
+	}
+}
+
+// terminate flushes the driver the message was written to, then ends the process or panics
+// if the message has the fatal or panic level.
+func terminate(m *Message) {
+	if !terminal(m.level) {
+		return
+	}
+
+	if flusher, ok := m.output.writer().(drivers.Flusher); ok {
+		flush(flusher)
+	}
+
+	if _, ok := m.level.(*level.LevelFatal); ok {
+		ExitFunc(1)
+		return
+	}
+
+	PanicFunc(string(m.content))
+}
@@ -0,0 +1,155 @@
+package log
+
+import (
+	"bytes"
+	"github.com/canghel3/telemetry/drivers"
+	"github.com/canghel3/telemetry/level"
+	"gotest.tools/v3/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// bufferedDriver keeps written entries until flushed.
+type bufferedDriver struct {
+	lock     sync.Mutex
+	buffered bytes.Buffer
+	flushed  bytes.Buffer
+}
+
+// newBufferedDriver returns a bufferedDriver that is unregistered from the flushed drivers once the test ends,
+// so that later calls to Flush do not walk through the drivers of finished tests.
+func newBufferedDriver(t *testing.T) *bufferedDriver {
+	driver := &bufferedDriver{}
+	t.Cleanup(func() { drivers.Unregister(driver) })
+	return driver
+}
+
+func (bd *bufferedDriver) Write(p []byte) (int, error) {
+	bd.lock.Lock()
+	defer bd.lock.Unlock()
+	return bd.buffered.Write(p)
+}
+
+func (bd *bufferedDriver) Flush() error {
+	bd.lock.Lock()
+	defer bd.lock.Unlock()
+	_, err := bd.buffered.WriteTo(&bd.flushed)
+	return err
+}
+
+func TestTerminalLevels(t *testing.T) {
+	t.Run("FATAL", func(t *testing.T) {
+		defer func(exit func(int)) { ExitFunc = exit }(ExitFunc)
+
+		driver := newBufferedDriver(t)
+		var code int
+		ExitFunc = func(c int) {
+			code = c
+			//everything must be delivered before exiting
+			assert.Equal(t, driver.buffered.Len(), 0)
+		}
+
+		toDriver := OutputDriver(driver)
+		tx := BeginTx().FlushOnExit()
+		tx.Append(toDriver.Info().Msg("pending transaction"))
+		abandoned := BeginTx()
+		abandoned.Append(toDriver.Info().Msg("abandoned transaction"))
+
+		toDriver.Fatal().Log("cannot continue")
+		assert.Equal(t, code, 1)
+
+		//the abandoned transaction did not opt in to FlushOnExit and is not logged
+		lines := bytes.Split(bytes.TrimSuffix(driver.flushed.Bytes(), []byte("\n")), []byte("\n"))
+		assert.Equal(t, len(lines), 2)
+		assert.Assert(t, bytes.HasSuffix(lines[0], []byte("INFO pending transaction")))
+		assert.Assert(t, bytes.HasSuffix(lines[1], []byte(level.Fatal().Type()+" cannot continue")))
+
+		//the transaction was committed by the fatal log
+		tx.Log()
+		assert.Equal(t, driver.buffered.Len(), 0)
+	})
+
+	t.Run("PANIC", func(t *testing.T) {
+		var buffer bytes.Buffer
+
+		defer func() {
+			r := recover()
+			assert.Equal(t, r, "something impossible happened")
+			assert.Assert(t, bytes.HasSuffix(buffer.Bytes(), []byte(level.Panic().Type()+" something impossible happened\n")))
+		}()
+
+		OutputDriver(&buffer).Panic().Log("something impossible happened")
+		t.Fatal("expected a panic")
+	})
+
+	t.Run("PANIC HOOK", func(t *testing.T) {
+		defer func(p func(string)) { PanicFunc = p }(PanicFunc)
+
+		var intercepted string
+		PanicFunc = func(msg string) {
+			intercepted = msg
+		}
+
+		var buffer bytes.Buffer
+		OutputDriver(&buffer).Panic().Logf("value %d out of range", 7)
+		assert.Equal(t, intercepted, "value 7 out of range")
+	})
+
+	t.Run("DISCARDED TRANSACTION IS NOT FLUSHED", func(t *testing.T) {
+		defer func(exit func(int)) { ExitFunc = exit }(ExitFunc)
+		ExitFunc = func(int) {}
+
+		var buffer bytes.Buffer
+		toBuffer := OutputDriver(&buffer)
+
+		tx := BeginTx().FlushOnExit()
+		tx.Append(toBuffer.Info().Msg("discarded"))
+		tx.Discard()
+
+		toBuffer.Fatal().Log("exit")
+		assert.Assert(t, !bytes.Contains(buffer.Bytes(), []byte("discarded")))
+	})
+
+	t.Run("TRACE", func(t *testing.T) {
+		var buffer bytes.Buffer
+
+		OutputDriver(&buffer).Trace().Log("entering")
+		assert.Assert(t, bytes.HasSuffix(buffer.Bytes(), []byte("TRACE entering\n")))
+	})
+}
+
+func TestFlushWebhookDriver(t *testing.T) {
+	var lock sync.Mutex
+	var received bytes.Buffer
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		io.Copy(&received, r.Body)
+	}))
+	defer server.Close()
+
+	driver := drivers.NewWebhookDriver(server.URL, drivers.WebhookOptions{BatchInterval: time.Hour})
+	defer driver.Close()
+
+	OutputDriver(driver).Error().Log("payment failed")
+	Flush()
+
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Assert(t, strings.HasSuffix(received.String(), " ERROR payment failed\n"), received.String())
+}
+
+func TestFlushUnregistersClosedDrivers(t *testing.T) {
+	driver := drivers.NewWebhookDriver("http://127.0.0.1:1", drivers.WebhookOptions{MaxRetries: -1})
+	OutputDriver(driver)
+	assert.Assert(t, slices.Contains(drivers.Registered(), drivers.Flusher(driver)))
+
+	driver.Close()
+	assert.Assert(t, !slices.Contains(drivers.Registered(), drivers.Flusher(driver)))
+}
@@ -0,0 +1,126 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/canghel3/telemetry/level"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// LevelState is the representation of a registered output used by LevelHandler.
+type LevelState struct {
+	Name string `json:"name"`
+	// Level is the lower case name of the minimum level. Empty when every level is logged.
+	Level string `json:"level"`
+	// Expires is set when the level is a temporary override.
+	Expires *time.Time `json:"expires,omitempty"`
+}
+
+// LevelRequest is the body of a PUT request handled by LevelHandler.
+type LevelRequest struct {
+	// Level is parsed with level.Parse. Empty removes the runtime override.
+	Level string `json:"level"`
+	// TTL is an optional duration, e.g. "15m", after which the previous level is restored.
+	TTL string `json:"ttl,omitempty"`
+}
+
+// LevelHandler returns a handler controlling the minimum level of the registered outputs.
+// Mounted under a prefix, e.g. with http.StripPrefix, it serves:
+//
+//	GET /        lists every registered output and its minimum level
+//	GET /{name}  returns the minimum level of the named output
+//	PUT /{name}  sets the minimum level of the named output from a LevelRequest
+func LevelHandler() http.Handler {
+	return http.HandlerFunc(serveLevel)
+}
+
+func serveLevel(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(r.URL.Path, "/")
+
+	switch {
+	case r.Method == http.MethodGet && len(name) == 0:
+		states := []LevelState{}
+		for _, n := range registered() {
+			if o, ok := Lookup(n); ok {
+				states = append(states, levelState(n, o))
+			}
+		}
+
+		writeJSON(w, http.StatusOK, states)
+	case r.Method == http.MethodGet:
+		o, ok := Lookup(name)
+		if !ok {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("unknown output %q", name))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, levelState(name, o))
+	case r.Method == http.MethodPut && len(name) > 0:
+		o, ok := Lookup(name)
+		if !ok {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("unknown output %q", name))
+			return
+		}
+
+		var req LevelRequest
+		err := json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request: %s", err.Error()))
+			return
+		}
+
+		var l level.Level
+		if len(req.Level) > 0 {
+			l, err = level.Parse(req.Level)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+		}
+
+		if len(req.TTL) == 0 {
+			o.SetLevel(l)
+			writeJSON(w, http.StatusOK, levelState(name, o))
+			return
+		}
+
+		ttl, err := time.ParseDuration(req.TTL)
+		if err != nil || ttl <= 0 || l == nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid ttl %q, a positive duration and a level are required", req.TTL))
+			return
+		}
+
+		o.SetLevelFor(l, ttl)
+		writeJSON(w, http.StatusOK, levelState(name, o))
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		writeError(w, http.StatusMethodNotAllowed, fmt.Sprintf("method %s not allowed", r.Method))
+	}
+}
+
+func levelState(name string, o *Output) LevelState {
+	state := LevelState{Name: name}
+
+	l, expires := o.MinLevel()
+	if l != nil {
+		state.Level = strings.ToLower(l.Type())
+	}
+
+	if !expires.IsZero() {
+		state.Expires = &expires
+	}
+
+	return state
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
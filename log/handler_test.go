@@ -0,0 +1,135 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/canghel3/telemetry/config"
+	"github.com/canghel3/telemetry/level"
+	"gotest.tools/v3/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func unregister(names ...string) {
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+
+	for _, name := range names {
+		delete(registry.outputs, name)
+	}
+}
+
+func TestLevelHandler(t *testing.T) {
+	var buffer bytes.Buffer
+
+	cfg := config.Default()
+	cfg.Level = "info"
+	api := Register("api", New(&buffer, cfg))
+	worker := Register("worker", New(&buffer, config.Default()))
+	defer unregister("api", "worker")
+
+	server := httptest.NewServer(http.StripPrefix("/levels", LevelHandler()))
+	defer server.Close()
+
+	do := func(t *testing.T, method, path, body string) (int, []byte) {
+		req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		assert.NilError(t, err)
+
+		resp, err := http.DefaultClient.Do(req)
+		assert.NilError(t, err)
+		defer resp.Body.Close()
+
+		var read bytes.Buffer
+		_, err = read.ReadFrom(resp.Body)
+		assert.NilError(t, err)
+		return resp.StatusCode, read.Bytes()
+	}
+
+	t.Run("LIST", func(t *testing.T) {
+		status, body := do(t, http.MethodGet, "/levels/", "")
+		assert.Equal(t, status, http.StatusOK)
+
+		var states []LevelState
+		assert.NilError(t, json.Unmarshal(body, &states))
+		assert.DeepEqual(t, states, []LevelState{{Name: "api", Level: "info"}, {Name: "worker", Level: ""}})
+	})
+
+	t.Run("GET", func(t *testing.T) {
+		status, body := do(t, http.MethodGet, "/levels/api", "")
+		assert.Equal(t, status, http.StatusOK)
+		assert.Equal(t, string(body), `{"name":"api","level":"info"}`+"\n")
+
+		status, _ = do(t, http.MethodGet, "/levels/unknown", "")
+		assert.Equal(t, status, http.StatusNotFound)
+	})
+
+	t.Run("PUT", func(t *testing.T) {
+		status, body := do(t, http.MethodPut, "/levels/worker", `{"level": "warning"}`)
+		assert.Equal(t, status, http.StatusOK)
+		assert.Equal(t, string(body), `{"name":"worker","level":"warn"}`+"\n")
+
+		buffer.Reset()
+		worker.Info().Log("hidden")
+		worker.Warn().Log("visible")
+		assert.Assert(t, !bytes.Contains(buffer.Bytes(), []byte("hidden")))
+		assert.Assert(t, bytes.Contains(buffer.Bytes(), []byte("visible")))
+
+		//an empty level removes the override
+		status, _ = do(t, http.MethodPut, "/levels/worker", `{"level": ""}`)
+		assert.Equal(t, status, http.StatusOK)
+		l, _ := worker.MinLevel()
+		assert.Assert(t, l == nil)
+	})
+
+	t.Run("PUT WITH TTL", func(t *testing.T) {
+		status, body := do(t, http.MethodPut, "/levels/api", `{"level": "debug", "ttl": "100ms"}`)
+		assert.Equal(t, status, http.StatusOK)
+
+		var state LevelState
+		assert.NilError(t, json.Unmarshal(body, &state))
+		assert.Equal(t, state.Level, "debug")
+		assert.Assert(t, state.Expires != nil)
+
+		l, _ := api.MinLevel()
+		assert.Equal(t, l.Type(), level.Debug().Type())
+
+		deadline := time.Now().Add(2 * time.Second)
+		for time.Now().Before(deadline) {
+			if l, _ = api.MinLevel(); l.Type() == level.Info().Type() {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		assert.Equal(t, l.Type(), level.Info().Type())
+	})
+
+	t.Run("TTL RESTORES PERMANENT OVERRIDE", func(t *testing.T) {
+		worker.SetLevel(level.Error())
+		worker.SetLevelFor(level.Trace(), time.Hour)
+		worker.SetLevelFor(level.Debug(), 50*time.Millisecond)
+
+		time.Sleep(200 * time.Millisecond)
+		l, expires := worker.MinLevel()
+		assert.Equal(t, l.Type(), level.Error().Type())
+		assert.Assert(t, expires.IsZero())
+		worker.SetLevel(nil)
+	})
+
+	t.Run("BAD REQUESTS", func(t *testing.T) {
+		status, body := do(t, http.MethodPut, "/levels/api", `{"level": "loud"}`)
+		assert.Equal(t, status, http.StatusBadRequest)
+		assert.Assert(t, bytes.Contains(body, []byte(`unknown level \"loud\"`)))
+
+		status, _ = do(t, http.MethodPut, "/levels/api", `{"level": "debug", "ttl": "soon"}`)
+		assert.Equal(t, status, http.StatusBadRequest)
+
+		status, _ = do(t, http.MethodPut, "/levels/api", `level=debug`)
+		assert.Equal(t, status, http.StatusBadRequest)
+
+		status, _ = do(t, http.MethodDelete, "/levels/api", "")
+		assert.Equal(t, status, http.StatusMethodNotAllowed)
+	})
+}
@@ -0,0 +1,97 @@
+package log
+
+import (
+	"github.com/canghel3/telemetry/level"
+	"time"
+)
+
+type levelOverride struct {
+	level level.Level
+	//expires is zero for permanent overrides.
+	expires time.Time
+	//previous is the permanent override restored when a temporary one expires.
+	previous *levelOverride
+}
+
+// SetLevel sets the minimum level of the output at runtime, taking precedence over the configured one.
+// A nil level removes the override, restoring the configured minimum level.
+func (o *Output) SetLevel(l level.Level) *Output {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	o.stopRevert()
+	if l == nil {
+		o.override.Store(nil)
+	} else {
+		o.override.Store(&levelOverride{level: l})
+	}
+
+	return o
+}
+
+// SetLevelFor sets the minimum level of the output at runtime for the given duration,
+// after which the minimum level in effect before any temporary override is restored.
+func (o *Output) SetLevelFor(l level.Level, ttl time.Duration) *Output {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	o.stopRevert()
+
+	previous := o.override.Load()
+	if previous != nil && !previous.expires.IsZero() {
+		previous = previous.previous
+	}
+
+	override := &levelOverride{level: l, expires: o.now().Add(ttl), previous: previous}
+	o.override.Store(override)
+	o.revert = time.AfterFunc(ttl, func() {
+		//only revert if the override was not replaced in the meantime
+		o.override.CompareAndSwap(override, previous)
+	})
+
+	return o
+}
+
+// MinLevel returns the minimum level of the output and, for temporary overrides, when it expires.
+// A nil level means every level is logged.
+// Named loggers without a level of their own inherit the minimum level of their parent.
+func (o *Output) MinLevel() (level.Level, time.Time) {
+	if override := o.override.Load(); override != nil {
+		return override.level, override.expires
+	}
+
+	if node := o.node.Load(); node != nil && node.level != nil {
+		return node.level, time.Time{}
+	}
+
+	if o.parent != nil {
+		l, _ := o.parent.MinLevel()
+		return l, time.Time{}
+	}
+
+	return o.compiled().minimum, time.Time{}
+}
+
+// enabled reports whether messages of the given level pass the minimum level of the output.
+// Unregistered custom levels have no severity to compare and always pass.
+func (o *Output) enabled(l level.Level) bool {
+	minimum, _ := o.MinLevel()
+	if minimum == nil {
+		return true
+	}
+
+	d, ok := level.Lookup(l)
+	if !ok {
+		return true
+	}
+
+	return d.Severity >= level.Severity(minimum)
+}
+
+// stopRevert cancels the pending revert of a temporary override. The caller must hold the lock.
+func (o *Output) stopRevert() {
+	if o.revert != nil {
+		o.revert.Stop()
+		o.revert = nil
+	}
+}
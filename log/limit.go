@@ -0,0 +1,174 @@
+package log
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Limits configures the rate limiting and duplicate suppression of an Output.
+type Limits struct {
+	// Rate is the number of messages logged per second on average. Zero disables rate limiting.
+	Rate float64
+	// Burst is the number of messages that can be logged at once before the rate applies. Defaults to 1.
+	Burst int
+	// DedupWindow collapses identical messages logged within the window of each other.
+	// The first message is logged, the repeats are counted and logged as a single line
+	// with a "(repeated N times)" suffix once no repeat occurred for the window. Zero disables it.
+	DedupWindow time.Duration
+	// Transactions applies the limits to messages of logged transactions, which are otherwise never dropped.
+	Transactions bool
+}
+
+// limiting applies Limits to the messages of an Output.
+type limiting struct {
+	limits Limits
+	bucket *bucket
+	dedup  *dedup
+}
+
+// allow reports whether the message passes the limits. tx is the transaction of the message, nil for messages logged alone.
+func (l *limiting) allow(m *Message, tx *Tx) bool {
+	//terminal levels are always delivered
+	if terminal(m.level) || (tx != nil && !l.limits.Transactions) {
+		return true
+	}
+
+	//repeats are counted before rate limiting, so that they do not consume tokens
+	if l.dedup != nil && !l.dedup.first(m, tx) {
+		return false
+	}
+
+	return l.bucket == nil || l.bucket.take(m.output.now())
+}
+
+// bucket is a token bucket refilled at rate tokens per second, holding at most burst tokens.
+type bucket struct {
+	lock   sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// take consumes a token if one is available at the time now, told by the clock of the output.
+func (b *bucket) take(now time.Time) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.last.IsZero() {
+		b.tokens = b.burst
+	} else {
+		b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+
+	b.tokens--
+	return true
+}
+
+// dedup suppresses messages identical to one logged within the window.
+type dedup struct {
+	window time.Duration
+
+	lock   sync.Mutex
+	bursts map[string]*burst
+}
+
+// burst tracks the repeats of a message, it ends once no repeat occurred for the window.
+type burst struct {
+	message *Message
+	//tx is the transaction the first message was logged in, so that the repeats carry its id, metadata and processors.
+	tx      *Tx
+	repeats int
+	last    time.Time
+	timer   *time.Timer
+}
+
+// first reports whether the message starts a burst of identical messages, counting it as a repeat otherwise.
+func (d *dedup) first(m *Message, tx *Tx) bool {
+	key := fmt.Sprint(m.level.Type(), "\x00", string(m.content), "\x00", m.resolvedMeta())
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if b, ok := d.bursts[key]; ok {
+		b.repeats++
+		b.last = m.output.now()
+		return false
+	}
+
+	//the message is reused once logged, the burst keeps a copy to log the repeats
+	b := &burst{message: m.clone(), tx: tx, last: m.output.now()}
+	b.timer = time.AfterFunc(d.window, func() { d.end(key, b) })
+	d.bursts[key] = b
+	return true
+}
+
+// end logs the number of repeats of the burst once no repeat occurred for the window.
+func (d *dedup) end(key string, b *burst) {
+	d.lock.Lock()
+	//the window is measured with the clock of the output, the timer only tells when to check it
+	if remaining := d.window - b.message.output.now().Sub(b.last); remaining > 0 {
+		b.timer.Reset(remaining)
+		d.lock.Unlock()
+		return
+	}
+
+	delete(d.bursts, key)
+	repeats := b.repeats
+	d.lock.Unlock()
+
+	if repeats == 0 {
+		return
+	}
+
+	m := *b.message
+	m.content = []byte(fmt.Sprintf("%s (repeated %d times)", b.message.content, repeats))
+	if b.tx != nil {
+		b.tx.write(m.output, []*Message{&m})
+		return
+	}
+
+	m.write()
+}
+
+// Limit sets the rate limiting and duplicate suppression of the output. The zero Limits removes them.
+// Messages with the fatal and panic levels are never dropped.
+// Named loggers without limits use the limits of their parent.
+func (o *Output) Limit(limits Limits) *Output {
+	if limits.Rate <= 0 && limits.DedupWindow <= 0 {
+		o.limiting = nil
+		return o
+	}
+
+	l := &limiting{limits: limits}
+	if limits.Rate > 0 {
+		l.bucket = &bucket{rate: limits.Rate, burst: float64(max(limits.Burst, 1))}
+	}
+
+	if limits.DedupWindow > 0 {
+		l.dedup = &dedup{window: limits.DedupWindow, bursts: make(map[string]*burst)}
+	}
+
+	o.limiting = l
+	return o
+}
+
+// allowed reports whether the limits of the output, inherited from the parent for named loggers, let the message through.
+// Messages of transactions are only limited when Limits.Transactions is set, tx being nil for messages logged alone.
+func (o *Output) allowed(m *Message, tx *Tx) bool {
+	if o.limiting != nil {
+		return o.limiting.allow(m, tx)
+	}
+
+	if o.parent != nil {
+		return o.parent.allowed(m, tx)
+	}
+
+	return true
+}
@@ -0,0 +1,165 @@
+package log
+
+import (
+	"bytes"
+	"github.com/canghel3/telemetry/encoder"
+	"github.com/canghel3/telemetry/telemetrytest"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/poll"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLimits(t *testing.T) {
+	t.Run("RATE", func(t *testing.T) {
+		var buffer bytes.Buffer
+		toBuffer := OutputDriver(&buffer).Limit(Limits{Rate: 1, Burst: 3})
+
+		for i := 0; i < 10; i++ {
+			toBuffer.Info().Logf("request %d", i)
+		}
+
+		assert.Equal(t, strings.Count(buffer.String(), "request"), 3)
+	})
+
+	t.Run("RATE WITH CLOCK", func(t *testing.T) {
+		clock := telemetrytest.NewClock(time.Date(2024, 3, 2, 10, 0, 0, 0, time.UTC))
+		var buffer bytes.Buffer
+		toBuffer := OutputDriver(&buffer).Clock(clock).Limit(Limits{Rate: 1, Burst: 1})
+
+		toBuffer.Info().Log("first")
+		toBuffer.Info().Log("dropped")
+		clock.Advance(time.Second)
+		toBuffer.Info().Log("refilled")
+
+		logged := buffer.String()
+		assert.Equal(t, strings.Count(logged, "\n"), 2)
+		assert.Assert(t, !strings.Contains(logged, "dropped"))
+		assert.Assert(t, strings.Contains(logged, "refilled\n"))
+	})
+
+	t.Run("CONCURRENT", func(t *testing.T) {
+		driver := newBufferedDriver(t)
+		toDriver := OutputDriver(driver).Limit(Limits{Rate: 1, Burst: 5, DedupWindow: time.Minute})
+
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				for j := 0; j < 50; j++ {
+					toDriver.Info().Logf("worker %d", i)
+				}
+			}(i)
+		}
+		wg.Wait()
+
+		driver.lock.Lock()
+		defer driver.lock.Unlock()
+		assert.Equal(t, strings.Count(driver.buffered.String(), "worker"), 5)
+	})
+
+	t.Run("DEDUP", func(t *testing.T) {
+		driver := newBufferedDriver(t)
+		toDriver := OutputDriver(driver).Limit(Limits{DedupWindow: 30 * time.Millisecond})
+
+		for i := 0; i < 5; i++ {
+			toDriver.Warn().Log("disk almost full")
+		}
+		toDriver.Error().Log("disk almost full")
+
+		time.Sleep(100 * time.Millisecond)
+
+		driver.lock.Lock()
+		logged := driver.buffered.String()
+		driver.lock.Unlock()
+
+		assert.Equal(t, strings.Count(logged, "WARN disk almost full\n"), 1)
+		assert.Equal(t, strings.Count(logged, "WARN disk almost full (repeated 4 times)\n"), 1)
+		assert.Equal(t, strings.Count(logged, "ERROR disk almost full\n"), 1)
+		assert.Equal(t, strings.Count(logged, "\n"), 3)
+	})
+
+	t.Run("DEDUP WITH CLOCK", func(t *testing.T) {
+		clock := telemetrytest.NewClock(time.Date(2024, 3, 2, 10, 0, 0, 0, time.UTC))
+		driver := newBufferedDriver(t)
+		toDriver := OutputDriver(driver).Clock(clock).Limit(Limits{DedupWindow: 30 * time.Millisecond})
+
+		toDriver.Warn().Log("disk almost full")
+		toDriver.Warn().Log("disk almost full")
+
+		logged := func() string {
+			driver.lock.Lock()
+			defer driver.lock.Unlock()
+			return driver.buffered.String()
+		}
+
+		//the window did not pass on the clock of the output, so the burst goes on
+		time.Sleep(100 * time.Millisecond)
+		assert.Assert(t, !strings.Contains(logged(), "repeated"))
+
+		clock.Advance(time.Second)
+		poll.WaitOn(t, func(poll.LogT) poll.Result {
+			if strings.Contains(logged(), "WARN disk almost full (repeated 1 times)\n") {
+				return poll.Success()
+			}
+			return poll.Continue("the burst did not end")
+		}, poll.WithTimeout(time.Second))
+	})
+
+	t.Run("TRANSACTIONS", func(t *testing.T) {
+		var buffer bytes.Buffer
+		toBuffer := OutputDriver(&buffer).Limit(Limits{Rate: 1, Burst: 1})
+
+		tx := BeginTx()
+		for i := 0; i < 3; i++ {
+			tx.Append(toBuffer.Info().Msgf("entry %d", i))
+		}
+		tx.Log()
+		assert.Equal(t, strings.Count(buffer.String(), "entry"), 3)
+
+		buffer.Reset()
+		toBuffer.Limit(Limits{Rate: 1, Burst: 1, Transactions: true})
+
+		tx = BeginTx()
+		for i := 0; i < 3; i++ {
+			tx.Append(toBuffer.Info().Msgf("entry %d", i))
+		}
+		tx.Log()
+		assert.Equal(t, strings.Count(buffer.String(), "entry"), 1)
+	})
+
+	t.Run("TRANSACTION REPEATS", func(t *testing.T) {
+		driver := newBufferedDriver(t)
+		toDriver := OutputDriver(driver).Limit(Limits{DedupWindow: 30 * time.Millisecond, Transactions: true})
+
+		var lock sync.Mutex
+		var hooked []string
+		tx := BeginTxWithMetadata(map[any]any{"order": 7}).Hook(HookFunc(func(e *encoder.Entry, err error) {
+			lock.Lock()
+			defer lock.Unlock()
+			hooked = append(hooked, e.TxID)
+		}))
+		for i := 0; i < 3; i++ {
+			tx.Append(toDriver.Warn().Msg("card declined"))
+		}
+		tx.Log()
+
+		time.Sleep(100 * time.Millisecond)
+
+		driver.lock.Lock()
+		lines := strings.Split(strings.TrimSuffix(driver.buffered.String(), "\n"), "\n")
+		driver.lock.Unlock()
+
+		//the repeats are written like the first message, with the id, metadata and hooks of the transaction
+		assert.Equal(t, len(lines), 2)
+		assert.Assert(t, strings.Contains(lines[1], tx.id), lines[1])
+		assert.Assert(t, strings.HasSuffix(lines[1], "WARN order:7 card declined (repeated 2 times)"), lines[1])
+
+		lock.Lock()
+		defer lock.Unlock()
+		assert.DeepEqual(t, hooked, []string{tx.id, tx.id})
+	})
+}
@@ -3,103 +3,312 @@ package log
 import (
 	"bytes"
 	"fmt"
+	"github.com/canghel3/telemetry/drivers"
+	"github.com/canghel3/telemetry/encoder"
 	"github.com/canghel3/telemetry/level"
 	"os"
-	"time"
+	"runtime"
+	"sync"
 )
 
+// Message is a single log entry being built. A message can be logged several times.
 type Message struct {
-	content  []byte
-	level    level.Level
-	metadata map[any]any
+	content []byte
+	//template is the format or content the message was created with, used to group similar messages when sampling.
+	template string
+	//args are the arguments of a transaction message whose formatting is deferred until the transaction is logged,
+	//so that lazy arguments are only evaluated for logged messages.
+	args  []any
+	level level.Level
+	//metadata is set by Metadata, the metadata of the output is used otherwise.
+	metadata    map[any]any
+	ownMetadata bool
+	//resolved is set once the lazy values of the metadata were evaluated into metadata.
+	resolved bool
 	output   *Output
+
+	err error
+	//withStack requests a stack trace to be captured when the message content is set.
+	withStack bool
+	stack     string
+
+	//e is the entry handed to the encoder, kept in the message so that logging does not allocate it.
+	e encoder.Entry
 }
 
+// maxPooledContent is the capacity above which the content of a released message is left to the garbage collector,
+// so that one huge message does not keep its memory for the lifetime of the pool.
+const maxPooledContent = 64 << 10
+
+// messages pools the copies Log and Logf work on, the messages returned to the user are never pooled.
+var messages = sync.Pool{New: func() any {
+	return new(Message)
+}}
+
+// newMessage returns a message that does not escape to the heap unless it is kept, e.g. by a transaction.
 func newMessage(output *Output, level level.Level) *Message {
-	return &Message{
-		content:  nil,
-		level:    level,
-		metadata: output.meta,
-		output:   output,
+	return &Message{level: level, output: output}
+}
+
+// scratch returns a pooled copy of the message for Log and Logf to fill and write,
+// so that logging neither modifies the message nor allocates. The copy must be released once written.
+func (m *Message) scratch() *Message {
+	s := messages.Get().(*Message)
+	content := s.content[:0]
+	*s = *m
+	s.content = content
+	return s
+}
+
+// release resets the pooled message and returns it to the pool.
+func (m *Message) release() {
+	content := m.content[:0]
+	if cap(content) > maxPooledContent {
+		content = nil
+	}
+
+	*m = Message{content: content}
+	messages.Put(m)
+}
+
+// meta returns the metadata of the message.
+func (m *Message) meta() map[any]any {
+	if m.ownMetadata {
+		return m.metadata
+	}
+
+	return m.output.metadata()
+}
+
+// resolvedMeta returns the metadata of the message with its lazy values evaluated, evaluating them only once
+// however many times the message is inspected.
+func (m *Message) resolvedMeta() map[any]any {
+	if !m.resolved {
+		m.metadata = encoder.ResolveMetadata(m.meta())
+		m.ownMetadata, m.resolved = true, true
 	}
+
+	return m.metadata
+}
+
+// clone returns a copy of the message that is not reused, owning its content and metadata.
+func (m *Message) clone() *Message {
+	c := &Message{
+		content:     bytes.Clone(m.content),
+		template:    m.template,
+		level:       m.level,
+		metadata:    m.meta(),
+		ownMetadata: true,
+		output:      m.output,
+		err:         m.err,
+		stack:       m.stack,
+	}
+
+	return c
 }
 
 // Metadata sets the metadata only for this message.
 // TODO: instead of overwriting metadata passed from the Output, store separate metadat for this message only
 func (m *Message) Metadata(meta map[any]any) *Message {
 	m.metadata = meta
+	m.ownMetadata = true
+	return m
+}
+
+// Err attaches the error to the message.
+// Its message, type and wrapped errors are rendered by every encoder,
+// as is the stack trace carried by the error, if any.
+func (m *Message) Err(err error) *Message {
+	m.err = err
+	return m
+}
+
+// Stack captures the stack trace of the caller when the message is logged,
+// unless the attached error already carries one.
+func (m *Message) Stack() *Message {
+	m.withStack = true
 	return m
 }
 
 // Msg is only meant for use in log transactions.
 func (m *Message) Msg(msg string) *Message {
-	m.content = []byte(msg)
+	m.template = msg
+	m.content = append(m.content[:0], msg...)
+	m.captureStack()
 	return m
 }
 
 // Msgf is only meant for use in log transactions.
+// When an argument implements encoder.LogValuer, the message is formatted when the transaction is logged,
+// so that the lazy arguments are only evaluated if the message is logged.
 func (m *Message) Msgf(msg string, format ...any) *Message {
-	m.content = []byte(fmt.Sprintf(msg, format...))
+	m.template = msg
+	m.content = m.content[:0]
+	if encoder.HasLogValuer(format) {
+		m.args = format
+	} else {
+		m.content = fmt.Appendf(m.content, msg, format...)
+	}
+	m.captureStack()
 	return m
 }
 
 // Log logs to the corresponding output driver.
 func (m *Message) Log(msg string) {
-	m.content = []byte(msg)
-	m.log()
+	if m.discarded() {
+		return
+	}
+
+	s := m.scratch()
+	s.template = msg
+	s.content = append(s.content, msg...)
+	s.captureStack()
+	s.log(nil)
+	s.release()
 }
 
 // Logf logs to the corresponding output driver based on the given format.
+// The message is only formatted when it passes level filtering and sampling,
+// arguments implementing encoder.LogValuer are evaluated then.
 func (m *Message) Logf(msg string, format ...any) {
-	m.content = []byte(fmt.Sprintf(msg, format...))
-	m.log()
+	if m.discarded() {
+		return
+	}
+
+	s := m.scratch()
+	s.template = msg
+	if len(format) == 0 {
+		s.content = fmt.Appendf(s.content, msg)
+	}
+	s.captureStack()
+	//the arguments are passed down rather than kept in the message, so that they do not escape to the heap
+	s.log(format)
+	s.release()
+}
+
+// discarded reports true if the level of the message is below the minimum level of the output,
+// so that nothing is formatted for it. Fatal and panic messages are never discarded since they terminate the process.
+func (m *Message) discarded() bool {
+	return !terminal(m.level) && !m.output.enabled(m.level)
 }
 
-func (m *Message) log() {
-	var content = m.content
-	if !m.output.config.Formatting.LogConfig.FormattingDisabled {
-		content = m.formatLogOutput()
+// captureStack records the stack trace of the caller of the method calling it, if requested.
+func (m *Message) captureStack() {
+	if !m.withStack {
+		return
 	}
 
-	_, err := m.output.driver.Write(content)
-	if err != nil {
-		//write the error encountered during writing to os.Stderr
-		//we could write to the log content driver because it implements the io.Writer,
-		//but if the content driver is fatally broken, the writing failure will be lost as well
-		//and debugging becomes more difficult.
-		fmt.Fprintf(os.Stderr, "failed to write log %s: %s\n", m.content, err.Error())
+	m.stack = encoder.ErrorStack(m.err)
+	if len(m.stack) == 0 {
+		//skip runtime.Callers, captureStack and the Message method called by the user
+		pcs := make([]uintptr, 64)
+		n := runtime.Callers(3, pcs)
+		m.stack = encoder.FormatStack(pcs[:n])
 	}
 }
 
-func (m *Message) formatLogOutput() []byte {
-	//default order: TIMESTAMP LEVEL METADATA BUFFER
-	var buffer bytes.Buffer
+// log writes the message if it passes filtering, formatting its template with the arguments once it passed sampling.
+func (m *Message) log(args []any) {
+	//fatal and panic levels deliver everything still pending before their own entry
+	//and are formatted regardless of filtering, since their content is passed to PanicFunc
+	if terminal(m.level) {
+		Flush()
+		m.format(args)
+		args = nil
+	}
+
+	if !m.output.enabled(m.level) || !m.output.sampled(m) {
+		terminate(m)
+		return
+	}
+
+	//duplicates are detected on the formatted content
+	m.format(args)
+	if !m.output.allowed(m, nil) {
+		terminate(m)
+		return
+	}
+
+	m.write()
+	terminate(m)
+}
 
-	// format timestamp
-	timestampFormat := "2006-01-02 15:04:05"
-	if len(m.output.config.Formatting.LogConfig.Timestamp) > 0 {
-		timestampFormat = m.output.config.Formatting.LogConfig.Timestamp
+// format formats the template with the arguments, resolving the lazy ones. Nil arguments leave the content as is.
+func (m *Message) format(args []any) {
+	if args == nil {
+		return
 	}
 
-	buffer.WriteString(time.Now().Format(timestampFormat))
-	buffer.WriteByte(' ')
+	m.content = encoder.AppendFormat(m.content[:0], m.template, args...)
+	m.args = nil
+}
 
-	// format level
-	buffer.WriteString(m.level.Type())
-	buffer.WriteByte(' ')
+// write encodes the message and writes it to the output driver.
+func (m *Message) write() {
+	//load the configuration once so a concurrent reload cannot mix settings within one entry
+	compiled := m.output.compiled()
+	cfg := compiled.cfg.Formatting.LogConfig
 
-	// format metadata
-	for k, v := range m.metadata {
-		fmt.Fprintf(&buffer, "%v:%v ", k, v)
+	e := m.entry("")
+	compiled.enrich(e)
+	if !m.output.process(e) {
+		return
 	}
+	compiled.redactor.Redact(e)
+	//truncation comes after redaction, which could otherwise miss a secret cut in half
+	truncating := m.output.truncation()
+	truncated := truncating.entry(e)
 
-	//if len(m.metadata) > 0 {
-	//	buffer.WriteByte(' ')
-	//}
+	var err error
+	driver := m.output.writer()
+	if formatted, ok := driver.(drivers.FormattedEntryWriter); ok {
+		err = formatted.WriteFormattedEntry(e, cfg)
+	} else if entryWriter, ok := driver.(drivers.EntryWriter); ok {
+		err = entryWriter.WriteEntry(e)
+	} else {
+		buffer := getBuffer()
+		if cfg.FormattingDisabled {
+			buffer.Write(e.Content)
+		} else {
+			m.output.enc().Encode(buffer, e, cfg)
+		}
 
-	// add content
-	buffer.Write(m.content)
-	buffer.WriteByte('\n')
+		if truncating != nil && truncating.encoded(buffer, 0, truncating.limits.MaxEntry) {
+			truncated = true
+		}
+
+		_, err = driver.Write(buffer.Bytes())
+		putBuffer(buffer)
+	}
+	truncating.count(truncated)
+
+	if err != nil {
+		//write the error encountered during writing to os.Stderr
+		//we could write to the log content driver because it implements the io.Writer,
+		//but if the content driver is fatally broken, the writing failure will be lost as well
+		//and debugging becomes more difficult.
+		fmt.Fprintf(os.Stderr, "failed to write log %s: %s\n", e.Content, err.Error())
+	}
+
+	m.output.afterWrite(e, err)
+}
+
+// entry returns the encoder representation of the message. The entry is reused by the next call.
+func (m *Message) entry(txID string) *encoder.Entry {
+	stack := m.stack
+	if len(stack) == 0 && m.err != nil {
+		stack = encoder.ErrorStack(m.err)
+	}
+
+	m.e = encoder.Entry{
+		Time:     m.output.now(),
+		Level:    m.level,
+		Metadata: m.resolvedMeta(),
+		Content:  m.content,
+		TxID:     txID,
+		Error:    encoder.NewError(m.err),
+		Stack:    stack,
+	}
 
-	return buffer.Bytes()
+	return &m.e
 }
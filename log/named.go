@@ -0,0 +1,135 @@
+package log
+
+import (
+	"fmt"
+	"github.com/canghel3/telemetry/config"
+	"github.com/canghel3/telemetry/drivers"
+	"github.com/canghel3/telemetry/level"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// nodeConfig holds the settings of a named logger taken from the loggers configuration section.
+type nodeConfig struct {
+	level  level.Level
+	meta   map[any]any
+	driver io.Writer
+}
+
+var hierarchy = struct {
+	lock sync.Mutex
+	//loggers is the loggers section applied by the last call to Configure.
+	loggers    map[string]config.LoggerConfig
+	configured bool
+	//files caches the file drivers opened for the loggers configuration, so that reconfiguring does not reopen them.
+	files map[string]io.Writer
+}{files: make(map[string]io.Writer)}
+
+// Named returns the logger with the given dot separated name, e.g. payments.stripe, creating it and its parents if needed.
+// A named logger inherits the minimum level, metadata, driver and encoder of its parent unless they are set on the logger itself,
+// either in code or in the loggers section of the configuration. Metadata is merged, the closest logger winning on conflicts.
+// Named loggers are registered under their name, making them controllable through LevelHandler.
+func Named(name string) *Output {
+	var parent *Output
+	if i := strings.LastIndex(name, "."); i > 0 {
+		parent = Named(name[:i])
+	}
+
+	//the hierarchy lock is always acquired before the registry lock
+	hierarchy.lock.Lock()
+	defer hierarchy.lock.Unlock()
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+
+	if o, ok := registry.outputs[name]; ok {
+		return o
+	}
+
+	o := &Output{name: name, parent: parent}
+	cfg := config.PkgConfiguration.Clone()
+	o.config.Store(&cfg)
+
+	loggers := hierarchy.loggers
+	if !hierarchy.configured {
+		loggers = config.PkgConfiguration.Loggers
+	}
+	o.configure(loggers)
+
+	registry.outputs[name] = o
+	return o
+}
+
+// Configure applies the loggers section of the configuration to the named loggers, creating the ones that do not exist yet.
+// Named loggers absent from the section lose the settings of any previous configuration and inherit from their parent again.
+// Until Configure is called, named loggers use the loggers section of config.PkgConfiguration.
+func Configure(cfg config.PkgConfig) {
+	for name := range cfg.Loggers {
+		Named(name)
+	}
+
+	hierarchy.lock.Lock()
+	defer hierarchy.lock.Unlock()
+
+	hierarchy.loggers = cfg.Clone().Loggers
+	hierarchy.configured = true
+
+	for _, name := range registered() {
+		if o, ok := Lookup(name); ok && len(o.name) > 0 {
+			o.configure(hierarchy.loggers)
+		}
+	}
+}
+
+// configure applies the settings of the logger from the loggers section. The caller must hold the hierarchy lock.
+func (o *Output) configure(loggers map[string]config.LoggerConfig) {
+	lc, ok := loggers[strings.ToLower(o.name)]
+	if !ok {
+		o.node.Store(nil)
+		return
+	}
+
+	node := &nodeConfig{}
+	if len(lc.Level) > 0 {
+		l, err := level.Parse(lc.Level)
+		if err == nil {
+			node.level = l
+		}
+	}
+
+	if len(lc.Metadata) > 0 {
+		node.meta = make(map[any]any, len(lc.Metadata))
+		for k, v := range lc.Metadata {
+			node.meta[k] = v
+		}
+	}
+
+	if len(lc.Driver) > 0 {
+		node.driver = configuredDriver(lc.Driver)
+	}
+
+	o.node.Store(node)
+}
+
+// configuredDriver returns the driver described by stdout, stderr or file:<path>. The caller must hold the hierarchy lock.
+func configuredDriver(driver string) io.Writer {
+	switch {
+	case driver == "stdout":
+		return stdout
+	case driver == "stderr":
+		return os.Stderr
+	case strings.HasPrefix(driver, "file:"):
+		name := strings.TrimPrefix(driver, "file:")
+		if file, ok := hierarchy.files[name]; ok {
+			return file
+		}
+
+		file := drivers.NewFileDriver(name)
+		hierarchy.files[name] = file
+		return file
+	default:
+		fmt.Fprintf(os.Stderr, "unknown driver %s, using stdout\n", driver)
+		return stdout
+	}
+}
@@ -0,0 +1,84 @@
+package log
+
+import (
+	"bytes"
+	"github.com/canghel3/telemetry/config"
+	"github.com/canghel3/telemetry/level"
+	"gotest.tools/v3/assert"
+	"testing"
+)
+
+func TestNamed(t *testing.T) {
+	defer unregister("shop", "shop.cart", "shop.cart.items", "bank", "bank.ledger")
+
+	t.Run("CREATES PARENTS", func(t *testing.T) {
+		items := Named("shop.cart.items")
+		assert.Equal(t, items, Named("shop.cart.items"))
+
+		cart, ok := Lookup("shop.cart")
+		assert.Assert(t, ok)
+		assert.Equal(t, items.parent, cart)
+
+		shop, ok := Lookup("shop")
+		assert.Assert(t, ok)
+		assert.Equal(t, cart.parent, shop)
+	})
+
+	t.Run("INHERITS FROM PARENTS", func(t *testing.T) {
+		var buffer bytes.Buffer
+
+		Named("shop").Driver(&buffer).Metadata(map[any]any{"team": "shop", "region": "eu"})
+		Named("shop.cart").Metadata(map[any]any{"team": "cart"})
+		Named("shop").SetLevel(level.Warn())
+		defer Named("shop").SetLevel(nil)
+
+		Named("shop.cart.items").Info().Log("hidden")
+		Named("shop.cart.items").Error().Log("visible")
+
+		assert.Assert(t, !bytes.Contains(buffer.Bytes(), []byte("hidden")))
+		assert.Assert(t, bytes.Contains(buffer.Bytes(), []byte("ERROR ")))
+		assert.Assert(t, bytes.Contains(buffer.Bytes(), []byte("team:cart ")))
+		assert.Assert(t, bytes.Contains(buffer.Bytes(), []byte("region:eu ")))
+		assert.Assert(t, !bytes.Contains(buffer.Bytes(), []byte("team:shop")))
+
+		//overriding the level of a child does not affect its parent
+		buffer.Reset()
+		Named("shop.cart").SetLevel(level.Debug())
+		Named("shop.cart.items").Debug().Log("child debug")
+		Named("shop").Debug().Log("parent debug")
+		assert.Assert(t, bytes.Contains(buffer.Bytes(), []byte("child debug")))
+		assert.Assert(t, !bytes.Contains(buffer.Bytes(), []byte("parent debug")))
+	})
+
+	t.Run("CONFIGURED", func(t *testing.T) {
+		var buffer bytes.Buffer
+
+		cfg, err := config.LoadBytes([]byte(`{
+			"loggers": {
+				"bank": {"level": "warn", "metadata": {"service": "bank"}},
+				"bank.ledger": {"level": "debug"}
+			}
+		}`), "json")
+		assert.NilError(t, err)
+
+		Configure(cfg)
+		defer Configure(config.PkgConfig{})
+
+		Named("bank").Driver(&buffer)
+		Named("bank").Info().Log("bank info")
+		Named("bank.ledger").Debug().Log("ledger debug")
+
+		assert.Assert(t, !bytes.Contains(buffer.Bytes(), []byte("bank info")))
+		assert.Assert(t, bytes.Contains(buffer.Bytes(), []byte("DEBUG service:bank ledger debug\n")))
+
+		//loggers removed from the configuration inherit from their parent again
+		buffer.Reset()
+		cfg.Loggers = map[string]config.LoggerConfig{"bank": {Level: "error"}}
+		Configure(cfg)
+
+		Named("bank.ledger").Warn().Log("ledger warn")
+		Named("bank.ledger").Error().Log("ledger error")
+		assert.Assert(t, !bytes.Contains(buffer.Bytes(), []byte("ledger warn")))
+		assert.Assert(t, bytes.Contains(buffer.Bytes(), []byte("ERROR ledger error\n")))
+	})
+}
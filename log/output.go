@@ -4,10 +4,20 @@ import (
 	"fmt"
 	"github.com/canghel3/telemetry/config"
 	"github.com/canghel3/telemetry/drivers"
+	"github.com/canghel3/telemetry/encoder"
+	"github.com/canghel3/telemetry/enrich"
 	"github.com/canghel3/telemetry/level"
-	"github.com/spf13/viper"
+	"github.com/canghel3/telemetry/redact"
 	"io"
+	"os"
 	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	stdout = drivers.NewStdoutDriver()
+	text   = encoder.Text()
 )
 
 type Output struct {
@@ -15,18 +25,55 @@ type Output struct {
 	// in order to return an exact shallow copy of your Output instance.
 	lock sync.Mutex
 
-	driver io.Writer
-	config config.PkgConfig
+	driver  io.Writer
+	encoder encoder.Encoder
+	//config is swapped atomically so a Watcher can replace it while messages are being logged.
+	config atomic.Pointer[config.PkgConfig]
+	//override is the minimum level set at runtime, it takes precedence over the configured one.
+	override atomic.Pointer[levelOverride]
+	//revert restores the previous minimum level once a temporary override expires. Guarded by lock.
+	revert *time.Timer
 
 	meta map[any]any
+
+	//sampling drops messages to control the log volume, nil logs every message.
+	sampling *sampling
+	//derived caches the state derived from the configuration in use.
+	derived atomic.Pointer[compiled]
+	//clock tells the time of the entries, the SystemClock when nil.
+	clock Clock
+	//processors and hooks are called before encoding and after writing every entry.
+	processors []Processor
+	hooks      []Hook
+	//limiting applies rate limiting and duplicate suppression, nil logs every message.
+	limiting *limiting
+	//truncating bounds the size of the entries, nil leaves them unbounded.
+	truncating *truncating
+
+	//name and parent are only set for named loggers, which inherit unset values from their parent.
+	name   string
+	parent *Output
+	//node holds the settings of a named logger from the loggers configuration section.
+	node atomic.Pointer[nodeConfig]
 }
 
 // Default initiates an Output instance with a stdout driver.
 func Default() *Output {
-	return &Output{
-		driver: drivers.NewStdoutDriver(),
-		config: config.PkgConfiguration,
+	return New(drivers.NewStdoutDriver(), config.PkgConfiguration)
+}
+
+// New initiates an Output instance for logging to the given driver using the given configuration.
+// The package wide config.PkgConfiguration is not consulted.
+func New(driver io.Writer, cfg config.PkgConfig) *Output {
+	o := &Output{
+		driver:  driver,
+		encoder: encoder.Text(),
 	}
+
+	cfg = cfg.Clone()
+	o.config.Store(&cfg)
+	register(driver)
+	return o
 }
 
 // File initiates an Output instance for logging to the specified file.
@@ -37,8 +84,20 @@ func File(name string) *Output {
 }
 
 // Stdout initiates an Output instance for logging to stdout.
+// When stdout is a terminal, entries are rendered for humans by the console encoder, see Console.
 func Stdout() *Output {
 	d := Default()
+	if encoder.IsTerminal(os.Stdout) {
+		d.encoder = encoder.Console()
+	}
+	return d
+}
+
+// Console initiates an Output instance for logging to stdout in a human-friendly format.
+// Colors are used only when stdout is a terminal and NO_COLOR is not set.
+func Console() *Output {
+	d := Default()
+	d.encoder = encoder.Console()
 	return d
 }
 
@@ -46,6 +105,7 @@ func Stdout() *Output {
 func OutputDriver(driver io.Writer) *Output {
 	l := Default()
 	l.driver = driver
+	register(driver)
 	return l
 }
 
@@ -65,36 +125,69 @@ func (o *Output) Debug() *Message {
 	return newMessage(o, level.Debug())
 }
 
+func (o *Output) Trace() *Message {
+	return newMessage(o, level.Trace())
+}
+
+// Fatal logs the message after flushing the transactions pending with FlushOnExit and every buffering driver,
+// then terminates the process by calling ExitFunc.
+// Messages with this level appended to a transaction do not terminate the process.
+func (o *Output) Fatal() *Message {
+	return newMessage(o, level.Fatal())
+}
+
+// Panic logs the message after flushing the transactions pending with FlushOnExit and every buffering driver,
+// then calls PanicFunc, which panics with the message content by default.
+// Messages with this level appended to a transaction do not panic.
+func (o *Output) Panic() *Message {
+	return newMessage(o, level.Panic())
+}
+
 func (o *Output) Level(custom level.Level) *Message {
 	return newMessage(o, custom)
 }
 
 // Settings overwrites your current Output instance configuration.
 // Returns a shallow copy of your Output instance.
+// Failing to load the configuration is logged to stdout and the copy keeps the current configuration.
 func (o *Output) Settings(file string) *Output {
+	n, err := o.LoadSettings(file)
+	if err != nil {
+		Stdout().Error().Log(fmt.Sprintf("failed to load config: %s", err.Error()))
+	}
+
+	return n
+}
+
+// LoadSettings overwrites your current Output instance configuration.
+// Returns a shallow copy of your Output instance and any error encountered while loading the configuration,
+// in which case the copy keeps the current configuration.
+// The copy keeps the driver, encoder and metadata of the instance, inherited ones included for named loggers.
+func (o *Output) LoadSettings(file string) (*Output, error) {
 	var n = new(Output)
 
 	o.lock.Lock()
-	n.driver = o.driver
-	n.config = o.config
+	n.driver = o.writer()
+	n.encoder = o.enc()
+	n.meta = o.metadata()
+	n.sampling = o.sampling
+	n.limiting = o.limiting
+	n.truncating = o.truncating
+	n.processors = o.processors
+	n.hooks = o.hooks
+	n.clock = o.clock
+	cfg := o.conf().Clone()
+	n.config.Store(&cfg)
 	o.lock.Unlock()
 
-	v := viper.New()
-	v.SetConfigFile(file)
-	err := v.ReadInConfig()
-
+	loaded := cfg.Clone()
+	err := config.DecodeFile(file, &loaded)
 	if err != nil {
-		Stdout().Error().Log(fmt.Sprintf("failed to read config: %s", err.Error()))
-		return n
+		return n, err
 	}
 
-	err = v.Unmarshal(&n.config)
-	if err != nil {
-		Stdout().Error().Log(fmt.Sprintf("failed to unmarshal config: %s", err.Error()))
-		return n
-	}
-
-	return n
+	n.config.Store(&loaded)
+	return n, nil
 }
 
 // Metadata sets the metadata for the output driver.
@@ -103,3 +196,130 @@ func (o *Output) Metadata(meta map[any]any) *Output {
 	o.meta = meta
 	return o
 }
+
+// Encoder sets the encoder used to render the entries of the output.
+func (o *Output) Encoder(e encoder.Encoder) *Output {
+	o.encoder = e
+	return o
+}
+
+// Driver sets the output driver the entries are written to.
+func (o *Output) Driver(driver io.Writer) *Output {
+	o.driver = driver
+	register(driver)
+	return o
+}
+
+// writer returns the driver of the output, inherited from the parent for named loggers.
+func (o *Output) writer() io.Writer {
+	if o.driver != nil {
+		return o.driver
+	}
+
+	if node := o.node.Load(); node != nil && node.driver != nil {
+		return node.driver
+	}
+
+	if o.parent != nil {
+		return o.parent.writer()
+	}
+
+	return stdout
+}
+
+// enc returns the encoder of the output, inherited from the parent for named loggers.
+func (o *Output) enc() encoder.Encoder {
+	if o.encoder != nil {
+		return o.encoder
+	}
+
+	if o.parent != nil {
+		return o.parent.enc()
+	}
+
+	return text
+}
+
+// metadata returns the metadata of the output, merged over the metadata of the parents for named loggers.
+func (o *Output) metadata() map[any]any {
+	node := o.node.Load()
+	if o.parent == nil && node == nil {
+		return o.meta
+	}
+
+	var meta map[any]any
+	if o.parent != nil {
+		meta = o.parent.metadata()
+	}
+
+	if node != nil && len(node.meta) > 0 {
+		meta = merge(meta, node.meta)
+	}
+
+	if len(o.meta) > 0 {
+		meta = merge(meta, o.meta)
+	}
+
+	return meta
+}
+
+// merge returns a new map holding the values of base overridden by the values of override.
+func merge(base, override map[any]any) map[any]any {
+	merged := make(map[any]any, len(base)+len(override))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range override {
+		merged[k] = v
+	}
+
+	return merged
+}
+
+// conf returns the configuration currently in use by the Output.
+func (o *Output) conf() *config.PkgConfig {
+	return o.config.Load()
+}
+
+// compiled holds the state derived from a configuration, so that it is not derived again for every entry.
+type compiled struct {
+	cfg *config.PkgConfig
+	//minimum is the parsed minimum level, nil when every level is logged.
+	minimum    level.Level
+	redactor   *redact.Redactor
+	enrichment map[any]any
+}
+
+// compiled returns the state derived from the configuration in use, deriving it again after the configuration is swapped.
+func (o *Output) compiled() *compiled {
+	cfg := o.conf()
+	if c := o.derived.Load(); c != nil && c.cfg == cfg {
+		return c
+	}
+
+	redactor, err := redact.New(cfg.Redaction)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid redaction config: %s\n", err.Error())
+	}
+
+	c := &compiled{cfg: cfg, redactor: redactor, enrichment: enrich.Metadata(cfg.Enrich)}
+	if len(cfg.Level) > 0 {
+		//invalid levels are rejected by config.Validate, an unparsable one logs every level
+		c.minimum, _ = level.Parse(cfg.Level)
+	}
+
+	o.derived.Store(c)
+	return c
+}
+
+// enrich adds the metadata of the configured enrichers to the entry, without overriding its own metadata.
+func (c *compiled) enrich(e *encoder.Entry) {
+	if len(c.enrichment) > 0 {
+		e.Metadata = merge(c.enrichment, e.Metadata)
+	}
+}
+
+// swap atomically replaces the configuration of the Output.
+func (o *Output) swap(cfg *config.PkgConfig) {
+	o.config.Store(cfg)
+}
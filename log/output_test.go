@@ -3,12 +3,17 @@ package log
 import (
 	"bytes"
 	"fmt"
+	"github.com/canghel3/telemetry/config"
+	"github.com/canghel3/telemetry/encoder"
 	"github.com/canghel3/telemetry/level"
+	"github.com/canghel3/telemetry/telemetrytest"
 	"gotest.tools/v3/assert"
 	"io"
 	"os"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 )
 
 const file = "xyz.log"
@@ -21,6 +26,20 @@ func (ed *errorDriver) Write(p []byte) (n int, err error) {
 	return 0, fmt.Errorf("intentional write error")
 }
 
+// formattingDriver records the formatting configuration every entry was written with.
+type formattingDriver struct {
+	timestamps []string
+}
+
+func (fd *formattingDriver) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func (fd *formattingDriver) WriteFormattedEntry(_ *encoder.Entry, cfg config.LogConfig) error {
+	fd.timestamps = append(fd.timestamps, cfg.Timestamp)
+	return nil
+}
+
 type customLevel struct {
 	levelType string
 }
@@ -915,6 +934,23 @@ func TestOutputToCustom(t *testing.T) {
 	})
 }
 
+func TestFormattedEntryWriter(t *testing.T) {
+	cfg := config.Default()
+	cfg.Formatting.LogConfig.Timestamp = "15:04"
+	cfg.Formatting.TxConfig.Timestamp = "15:04:05"
+
+	driver := &formattingDriver{}
+	toDriver := New(driver, cfg)
+
+	toDriver.Info().Log("logged")
+	tx := BeginTx()
+	tx.Append(toDriver.Info().Msg("in transaction"))
+	tx.Log()
+
+	//entries are rendered with the configuration of the output, the transaction one for transactions
+	assert.DeepEqual(t, driver.timestamps, []string{"15:04", "15:04:05"})
+}
+
 func BenchmarkOutputToFile(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		//58k characters word below drops performance to 1101365 ns/op ~= 1.1014ms
@@ -925,3 +961,306 @@ func BenchmarkOutputToFile(b *testing.B) {
 		Stdout().Info().Log("BBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBB")
 	}
 }
+
+func TestLoadSettings(t *testing.T) {
+	t.Run("INVALID CONFIG", func(t *testing.T) {
+		name := t.TempDir() + "/telemetry.json"
+		err := os.WriteFile(name, []byte(`{"formatting": {"log": {"feild_order": {"timestamp": 1}}}}`), 0600)
+		assert.NilError(t, err)
+
+		o := Stdout()
+		n, err := o.LoadSettings(name)
+		assert.ErrorContains(t, err, "formatting.log.feild_order: unknown key")
+		assert.DeepEqual(t, *n.conf(), *o.conf())
+	})
+
+	t.Run("VALID CONFIG", func(t *testing.T) {
+		name := t.TempDir() + "/telemetry.json"
+		err := os.WriteFile(name, []byte(`{"formatting": {"log": {"timestamp": "15:04"}}}`), 0600)
+		assert.NilError(t, err)
+
+		o := Stdout()
+		n, err := o.LoadSettings(name)
+		assert.NilError(t, err)
+		assert.Equal(t, n.conf().Formatting.LogConfig.Timestamp, "15:04")
+		assert.Equal(t, o.conf().Formatting.LogConfig.Timestamp, "2006-01-02 15:04:05")
+	})
+
+	t.Run("KEEPS METADATA", func(t *testing.T) {
+		name := t.TempDir() + "/telemetry.json"
+		err := os.WriteFile(name, []byte(`{"formatting": {"log": {"timestamp": "15:04"}}}`), 0600)
+		assert.NilError(t, err)
+
+		var buffer bytes.Buffer
+		n, err := OutputDriver(&buffer).Metadata(map[any]any{"service": "api"}).LoadSettings(name)
+		assert.NilError(t, err)
+
+		n.Info().Log("loaded")
+		assert.Assert(t, bytes.HasSuffix(buffer.Bytes(), []byte(" INFO service:api loaded\n")), buffer.String())
+	})
+}
+
+func TestNew(t *testing.T) {
+	var buffer bytes.Buffer
+
+	cfg := config.Default()
+	cfg.Formatting.LogConfig.FormattingDisabled = true
+
+	New(&buffer, cfg).Info().Log("explicit config")
+	assert.Equal(t, buffer.String(), "explicit config")
+}
+
+func TestTransactionBlock(t *testing.T) {
+	var console, text bytes.Buffer
+
+	toConsole := OutputDriver(&console).Encoder(encoder.Console().Colors(false))
+	toText := OutputDriver(&text)
+
+	tx := BeginTx()
+	tx.Append(toConsole.Info().Msg("first"))
+	tx.Append(toText.Info().Msg("second"))
+	tx.Append(toConsole.Warn().Msg("third"))
+	tx.Log()
+
+	lines := bytes.Split(bytes.TrimSuffix(console.Bytes(), []byte("\n")), []byte("\n"))
+	assert.Equal(t, len(lines), 3)
+	assert.Equal(t, string(lines[0]), "┌ TRANSACTION "+tx.id)
+	assert.Assert(t, bytes.HasPrefix(lines[1], []byte("│ ")) && bytes.HasSuffix(lines[1], []byte(" INFO  first")))
+	assert.Assert(t, bytes.HasPrefix(lines[2], []byte("└ ")) && bytes.HasSuffix(lines[2], []byte(" WARN  third")))
+
+	assert.Assert(t, bytes.Contains(text.Bytes(), []byte("TRANSACTION "+tx.id+" | INFO second\n")))
+}
+
+func TestErrors(t *testing.T) {
+	t.Run("ERROR CHAIN", func(t *testing.T) {
+		var buffer bytes.Buffer
+
+		err := fmt.Errorf("failed to charge: %w", io.ErrUnexpectedEOF)
+		OutputDriver(&buffer).Error().Err(err).Log("payment")
+
+		expected := `ERROR payment error:"failed to charge: unexpected EOF" error.type:*fmt.wrapError error.cause:"unexpected EOF"(*errors.errorString)` + "\n"
+		assert.Assert(t, bytes.HasSuffix(buffer.Bytes(), []byte(expected)))
+	})
+
+	t.Run("STACK CAPTURED AT LOG TIME", func(t *testing.T) {
+		var buffer bytes.Buffer
+
+		OutputDriver(&buffer).Error().Stack().Log("with stack")
+		assert.Assert(t, bytes.Contains(buffer.Bytes(), []byte("ERROR with stack\n\tgithub.com/canghel3/telemetry/log.TestErrors.func2\n")))
+
+		buffer.Reset()
+		tx := BeginTx()
+		tx.Append(OutputDriver(&buffer).Error().Err(io.EOF).Stack().Msg("in transaction"))
+		tx.Log()
+		assert.Assert(t, bytes.Contains(buffer.Bytes(), []byte("ERROR in transaction error:\"EOF\" error.type:*errors.errorString\n\tgithub.com/canghel3/telemetry/log.TestErrors.func2\n")))
+	})
+}
+
+func TestMessageReuse(t *testing.T) {
+	var buffer bytes.Buffer
+	toBuffer := OutputDriver(&buffer)
+
+	m := toBuffer.Warn().Metadata(map[any]any{"order": 7})
+	m.Log("first")
+	m.Logf("second %d", 2)
+	m.Log("third")
+
+	lines := strings.Split(strings.TrimSuffix(buffer.String(), "\n"), "\n")
+	assert.Equal(t, len(lines), 3)
+	assert.Assert(t, strings.HasSuffix(lines[0], "WARN order:7 first"), lines[0])
+	assert.Assert(t, strings.HasSuffix(lines[1], "WARN order:7 second 2"), lines[1])
+	assert.Assert(t, strings.HasSuffix(lines[2], "WARN order:7 third"), lines[2])
+
+	//disabled messages can be reused as well
+	disabled := discardOutput().Debug()
+	disabled.Log("first")
+	disabled.Logf("second %d", 2)
+}
+
+func TestMinimumLevel(t *testing.T) {
+	var buffer bytes.Buffer
+
+	cfg := config.Default()
+	cfg.Level = "warn"
+	toBuffer := New(&buffer, cfg)
+
+	toBuffer.Debug().Log("dropped debug")
+	toBuffer.Info().Log("dropped info")
+	toBuffer.Warn().Log("kept warn")
+	toBuffer.Error().Log("kept error")
+	//unregistered custom levels have no severity and are never filtered out
+	toBuffer.Level(level.Custom("UNREGISTERED_MAJOR")).Log("kept custom")
+
+	tx := BeginTx()
+	tx.Append(toBuffer.Info().Msg("dropped from transaction"))
+	tx.Append(toBuffer.Error().Msg("kept in transaction"))
+	tx.Log()
+
+	assert.Assert(t, !bytes.Contains(buffer.Bytes(), []byte("dropped")))
+	assert.Assert(t, bytes.Contains(buffer.Bytes(), []byte("WARN kept warn\n")))
+	assert.Assert(t, bytes.Contains(buffer.Bytes(), []byte("ERROR kept error\n")))
+	assert.Assert(t, bytes.Contains(buffer.Bytes(), []byte("UNREGISTERED_MAJOR kept custom\n")))
+	assert.Assert(t, bytes.Contains(buffer.Bytes(), []byte("ERROR kept in transaction\n")))
+}
+
+func TestRedaction(t *testing.T) {
+	cfg := config.Default()
+	cfg.Redaction = config.RedactionConfig{Keys: []string{"*password"}, Patterns: []string{"bearer_token"}}
+
+	var buffer bytes.Buffer
+	toBuffer := New(&buffer, cfg).Metadata(map[any]any{"db_password": "hunter2"})
+	toBuffer.Info().Logf("calling api with %s", "Bearer abc")
+
+	tx := BeginTxWithMetadata(map[any]any{"password": "hunter2"})
+	tx.Append(toBuffer.Info().Msg("in transaction"))
+	tx.Log()
+
+	assert.Assert(t, bytes.Contains(buffer.Bytes(), []byte("db_password:[REDACTED] calling api with Bearer [REDACTED]\n")))
+	assert.Assert(t, bytes.Contains(buffer.Bytes(), []byte("password:[REDACTED] in transaction\n")))
+	assert.Assert(t, !bytes.Contains(buffer.Bytes(), []byte("hunter2")))
+}
+
+func TestEnrichment(t *testing.T) {
+	t.Setenv("ENVIRONMENT", "production")
+
+	cfg := config.Default()
+	cfg.Enrich = config.EnrichConfig{Fields: []string{"environment", "service"}, Service: "payments"}
+
+	var buffer bytes.Buffer
+	toBuffer := New(&buffer, cfg).Metadata(map[any]any{"environment": "overridden"})
+	toBuffer.Info().Log("message")
+
+	tx := BeginTx()
+	tx.Append(New(&buffer, cfg).Info().Msg("transaction"))
+	tx.Log()
+
+	lines := strings.Split(strings.TrimSuffix(buffer.String(), "\n"), "\n")
+	assert.Equal(t, len(lines), 2)
+	//the metadata of the output overrides the enrichers
+	assert.Assert(t, strings.Contains(lines[0], "environment:overridden "))
+	assert.Assert(t, strings.Contains(lines[0], "service:payments "))
+	assert.Assert(t, strings.Contains(lines[1], "environment:production "))
+	assert.Assert(t, strings.Contains(lines[1], "service:payments "))
+}
+
+func TestClock(t *testing.T) {
+	clock := telemetrytest.NewClock(time.Date(2024, 3, 2, 10, 0, 0, 0, time.UTC))
+
+	cfg := config.Default()
+	cfg.Formatting.LogConfig.Timestamp = time.RFC3339
+	cfg.Formatting.LogConfig.Timezone = "+02:00"
+	cfg.Formatting.TxConfig.Timestamp = time.RFC3339
+	cfg.Formatting.TxConfig.Timezone = "UTC"
+
+	var buffer bytes.Buffer
+	toBuffer := New(&buffer, cfg).Clock(clock)
+
+	toBuffer.Info().Log("first")
+	clock.Advance(time.Minute)
+	toBuffer.Info().Log("second")
+	assert.Equal(t, buffer.String(), "2024-03-02T12:00:00+02:00 INFO first\n2024-03-02T12:01:00+02:00 INFO second\n")
+
+	//temporary levels expire according to the clock of the output
+	toBuffer.SetLevelFor(level.Debug(), time.Hour)
+	_, expires := toBuffer.MinLevel()
+	assert.Assert(t, expires.Equal(clock.Now().Add(time.Hour)))
+	toBuffer.SetLevel(nil)
+
+	//the clock of the transaction overrides the clock of the output
+	buffer.Reset()
+	tx := BeginTx().Clock(telemetrytest.NewClock(time.Date(2024, 3, 2, 8, 0, 0, 0, time.UTC)))
+	tx.Append(toBuffer.Info().Msg("in transaction"))
+	tx.Log()
+	assert.Assert(t, strings.HasPrefix(buffer.String(), "2024-03-02T08:00:00Z TRANSACTION "))
+}
+
+func TestLazy(t *testing.T) {
+	var evaluated []string
+	lazy := func(name string) encoder.Lazy {
+		return func() any {
+			evaluated = append(evaluated, name)
+			return name
+		}
+	}
+
+	cfg := config.Default()
+	cfg.Level = "info"
+
+	var buffer bytes.Buffer
+	toBuffer := New(&buffer, cfg)
+	toBuffer.Debug().Logf("disabled %s", lazy("disabled"))
+	toBuffer.Debug().Metadata(map[any]any{"key": lazy("disabled metadata")}).Log("disabled")
+	toBuffer.Info().Metadata(map[any]any{"key": lazy("metadata")}).Logf("enabled %s", lazy("argument"))
+
+	sampled := New(&buffer, cfg).Sampler(SamplerFunc(func(level.Level, string) bool { return false }), 0)
+	sampled.Info().Logf("sampled %s", lazy("sampled"))
+
+	tx := BeginTxWithMetadata(map[any]any{"tx": lazy("tx metadata")})
+	tx.Append(toBuffer.Debug().Msgf("disabled in transaction %s", lazy("disabled in transaction")))
+	tx.Append(toBuffer.Info().Msgf("in transaction %s", lazy("in transaction")))
+	assert.DeepEqual(t, evaluated, []string{"argument", "metadata"})
+	tx.Log()
+
+	assert.DeepEqual(t, evaluated, []string{"argument", "metadata", "in transaction", "tx metadata"})
+	assert.Assert(t, strings.Contains(buffer.String(), "INFO key:metadata enabled argument\n"), buffer.String())
+	assert.Assert(t, strings.Contains(buffer.String(), "INFO tx:tx metadata in transaction in transaction\n"), buffer.String())
+
+	//lazy values are evaluated once, however many times the message is inspected
+	evaluations := make(map[string]int)
+	counted := func(name string) encoder.Lazy {
+		return func() any {
+			evaluations[name]++
+			return name
+		}
+	}
+
+	t.Run("FATAL EVALUATES ONCE", func(t *testing.T) {
+		defer func(exit func(int)) { ExitFunc = exit }(ExitFunc)
+		ExitFunc = func(int) {}
+
+		var buffer bytes.Buffer
+		OutputDriver(&buffer).Fatal().Logf("cannot continue: %s", counted("fatal"))
+		assert.Equal(t, evaluations["fatal"], 1)
+		assert.Assert(t, strings.HasSuffix(buffer.String(), " cannot continue: fatal\n"), buffer.String())
+	})
+
+	t.Run("DEDUP EVALUATES ONCE", func(t *testing.T) {
+		var buffer bytes.Buffer
+		toBuffer := OutputDriver(&buffer).Limit(Limits{DedupWindow: time.Hour})
+		toBuffer.Info().Metadata(map[any]any{"key": counted("dedup")}).Log("deduplicated")
+		assert.Equal(t, evaluations["dedup"], 1)
+		assert.Assert(t, strings.HasSuffix(buffer.String(), " INFO key:dedup deduplicated\n"), buffer.String())
+	})
+}
+
+func TestMultiline(t *testing.T) {
+	cfg := config.Default()
+	cfg.Formatting.LogConfig.Timestamp = time.RFC3339
+	cfg.Formatting.LogConfig.Timezone = "UTC"
+	cfg.Formatting.LogConfig.Multiline = "indent"
+	cfg.Formatting.TxConfig = config.TxConfig(cfg.Formatting.LogConfig)
+	cfg.Formatting.TxConfig.Multiline = "escape"
+
+	var buffer bytes.Buffer
+	toBuffer := New(&buffer, cfg).Clock(telemetrytest.NewClock(time.Date(2024, 3, 2, 10, 0, 0, 0, time.UTC)))
+
+	//a forged entry must not be mistaken for a real one
+	toBuffer.Info().Logf("user %s logged in", "eve\n2024-03-02T10:00:00Z ERROR forged\x1b[2J")
+	assert.Equal(t, buffer.String(), "2024-03-02T10:00:00Z INFO user eve\n\t2024-03-02T10:00:00Z ERROR forged\\x1b[2J logged in\n")
+
+	//trailing line feeds do not leave a line holding only the prefix
+	buffer.Reset()
+	toBuffer.Info().Log("done\r\n\n")
+	assert.Equal(t, buffer.String(), "2024-03-02T10:00:00Z INFO done\n")
+
+	//the text is written as is by default
+	buffer.Reset()
+	New(&buffer, config.Default()).Info().Log("first\nsecond")
+	assert.Assert(t, strings.HasSuffix(buffer.String(), " INFO first\nsecond\n"), buffer.String())
+
+	buffer.Reset()
+	tx := BeginTx()
+	tx.Append(toBuffer.Info().Msg("first\nsecond"))
+	tx.Log()
+	assert.Assert(t, strings.HasSuffix(buffer.String(), " | INFO first\\nsecond\n"), buffer.String())
+}
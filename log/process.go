@@ -0,0 +1,102 @@
+package log
+
+import (
+	"github.com/canghel3/telemetry/encoder"
+)
+
+// Processor inspects an entry before it is encoded. It can enrich or transform the entry,
+// or drop it by returning false, in which case the following processors are not called.
+// The metadata map of the entry is shared with the output and the transaction,
+// processors must replace it rather than modify it.
+type Processor interface {
+	Process(e *encoder.Entry) bool
+}
+
+// ProcessorFunc adapts a function to the Processor interface.
+type ProcessorFunc func(e *encoder.Entry) bool
+
+func (f ProcessorFunc) Process(e *encoder.Entry) bool {
+	return f(e)
+}
+
+// Hook is called after an entry was written to the output driver, with the error returned by the driver.
+// Entries dropped before being written do not reach hooks.
+// The entry is reused once the hook returns, hooks keeping it must copy it.
+type Hook interface {
+	AfterWrite(e *encoder.Entry, err error)
+}
+
+// HookFunc adapts a function to the Hook interface.
+type HookFunc func(e *encoder.Entry, err error)
+
+func (f HookFunc) AfterWrite(e *encoder.Entry, err error) {
+	f(e, err)
+}
+
+// Process appends processors to the output. Processors are called in order for every entry of the output,
+// including entries of transactions, before the processors of the transaction.
+// Named loggers call the processors of their parents first.
+func (o *Output) Process(processors ...Processor) *Output {
+	o.processors = append(o.processors, processors...)
+	return o
+}
+
+// Hook appends hooks called after every entry of the output was written, including entries of transactions.
+// Named loggers call the hooks of their parents first.
+func (o *Output) Hook(hooks ...Hook) *Output {
+	o.hooks = append(o.hooks, hooks...)
+	return o
+}
+
+// process passes the entry through the processors of the parents and of the output,
+// reporting whether it must be written.
+func (o *Output) process(e *encoder.Entry) bool {
+	if o.parent != nil && !o.parent.process(e) {
+		return false
+	}
+
+	return processAll(o.processors, e)
+}
+
+// afterWrite calls the hooks of the parents and of the output.
+func (o *Output) afterWrite(e *encoder.Entry, err error) {
+	if o.parent != nil {
+		o.parent.afterWrite(e, err)
+	}
+
+	hookAll(o.hooks, e, err)
+}
+
+// Process appends processors called for every entry of the transaction, after the processors of its output.
+func (tx *Tx) Process(processors ...Processor) *Tx {
+	tx.lock.Lock()
+	defer tx.lock.Unlock()
+
+	tx.processors = append(tx.processors, processors...)
+	return tx
+}
+
+// Hook appends hooks called after every entry of the transaction was written, after the hooks of its output.
+func (tx *Tx) Hook(hooks ...Hook) *Tx {
+	tx.lock.Lock()
+	defer tx.lock.Unlock()
+
+	tx.hooks = append(tx.hooks, hooks...)
+	return tx
+}
+
+func processAll(processors []Processor, e *encoder.Entry) bool {
+	for _, p := range processors {
+		if !p.Process(e) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func hookAll(hooks []Hook, e *encoder.Entry, err error) {
+	for _, h := range hooks {
+		h.AfterWrite(e, err)
+	}
+}
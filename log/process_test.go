@@ -0,0 +1,77 @@
+package log
+
+import (
+	"bytes"
+	"github.com/canghel3/telemetry/encoder"
+	"github.com/canghel3/telemetry/level"
+	"gotest.tools/v3/assert"
+	"strings"
+	"testing"
+)
+
+func TestProcessors(t *testing.T) {
+	enrich := ProcessorFunc(func(e *encoder.Entry) bool {
+		e.Metadata = merge(e.Metadata, map[any]any{"host": "web-1"})
+		return true
+	})
+	dropDebug := ProcessorFunc(func(e *encoder.Entry) bool {
+		return e.Level.Type() != level.Debug().Type()
+	})
+	upper := ProcessorFunc(func(e *encoder.Entry) bool {
+		e.Content = bytes.ToUpper(e.Content)
+		return true
+	})
+
+	t.Run("MESSAGES", func(t *testing.T) {
+		var buffer bytes.Buffer
+		var written []string
+		toBuffer := OutputDriver(&buffer).Process(dropDebug, enrich, upper).Hook(HookFunc(func(e *encoder.Entry, err error) {
+			written = append(written, string(e.Content))
+		}))
+
+		toBuffer.Debug().Log("dropped")
+		toBuffer.Info().Log("kept")
+
+		assert.Equal(t, strings.Count(buffer.String(), "\n"), 1)
+		assert.Assert(t, strings.Contains(buffer.String(), "INFO host:web-1 KEPT\n"))
+		assert.DeepEqual(t, written, []string{"KEPT"})
+	})
+
+	t.Run("TRANSACTIONS", func(t *testing.T) {
+		var buffer bytes.Buffer
+		var order []string
+		toBuffer := OutputDriver(&buffer).Process(enrich).Hook(HookFunc(func(e *encoder.Entry, err error) {
+			order = append(order, "output "+string(e.Content))
+		}))
+
+		tx := BeginTx().Process(dropDebug, upper).Hook(HookFunc(func(e *encoder.Entry, err error) {
+			order = append(order, "tx "+string(e.Content))
+		}))
+		tx.Append(toBuffer.Debug().Msg("dropped"))
+		tx.Append(toBuffer.Info().Msg("kept"))
+		tx.Log()
+
+		assert.Equal(t, strings.Count(buffer.String(), "\n"), 1)
+		assert.Assert(t, strings.Contains(buffer.String(), "INFO host:web-1 KEPT\n"))
+		assert.DeepEqual(t, order, []string{"output KEPT", "tx KEPT"})
+	})
+
+	t.Run("WRITE ERRORS", func(t *testing.T) {
+		var failed error
+		OutputDriver(&errorDriver{}).Hook(HookFunc(func(e *encoder.Entry, err error) {
+			failed = err
+		})).Info().Log("lost")
+
+		assert.ErrorContains(t, failed, "")
+	})
+
+	t.Run("NAMED", func(t *testing.T) {
+		defer unregister("pipeline", "pipeline.child")
+
+		var buffer bytes.Buffer
+		Named("pipeline").Driver(&buffer).Process(enrich)
+		Named("pipeline.child").Process(upper).Info().Log("inherited")
+
+		assert.Assert(t, strings.Contains(buffer.String(), "INFO host:web-1 INHERITED\n"))
+	})
+}
@@ -0,0 +1,7 @@
+//go:build race
+
+package log
+
+func init() {
+	raceEnabled = true
+}
@@ -0,0 +1,44 @@
+package log
+
+import (
+	"sort"
+	"sync"
+)
+
+var registry = struct {
+	lock    sync.RWMutex
+	outputs map[string]*Output
+}{outputs: make(map[string]*Output)}
+
+// Register names the output, making it controllable at runtime, e.g. through LevelHandler.
+// Registering another output under the same name replaces the previous one.
+func Register(name string, o *Output) *Output {
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+
+	registry.outputs[name] = o
+	return o
+}
+
+// Lookup returns the output registered under the given name.
+func Lookup(name string) (*Output, bool) {
+	registry.lock.RLock()
+	defer registry.lock.RUnlock()
+
+	o, ok := registry.outputs[name]
+	return o, ok
+}
+
+// registered returns the names of every registered output in lexical order.
+func registered() []string {
+	registry.lock.RLock()
+	defer registry.lock.RUnlock()
+
+	names := make([]string, 0, len(registry.outputs))
+	for name := range registry.outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,190 @@
+package log
+
+import (
+	"fmt"
+	"github.com/canghel3/telemetry/level"
+	"math/rand/v2"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Sampler decides which messages of an Output are logged.
+type Sampler interface {
+	// Sample reports whether the message with the level and template is logged.
+	// The template is the format given to Logf and Msgf, or the content given to Log and Msg.
+	Sample(l level.Level, template string) bool
+}
+
+// SamplerFunc adapts a function to the Sampler interface.
+type SamplerFunc func(l level.Level, template string) bool
+
+func (f SamplerFunc) Sample(l level.Level, template string) bool {
+	return f(l, template)
+}
+
+type samplingKey struct {
+	level    string
+	template string
+}
+
+// CountSampler logs the first messages of every level and template within each interval, then every Mth.
+type CountSampler struct {
+	first      uint64
+	thereafter uint64
+	interval   time.Duration
+	clock      Clock
+
+	lock sync.Mutex
+	//counters are reset at the end of every interval, so that their number is bounded by the distinct messages of one interval.
+	counters map[samplingKey]uint64
+	reset    time.Time
+}
+
+// NewCountSampler returns a sampler logging the first messages with the same level and template within the interval,
+// then every thereafter-th one. A thereafter of 0 drops every message past the first ones until the interval ends.
+func NewCountSampler(interval time.Duration, first, thereafter uint64) *CountSampler {
+	return &CountSampler{
+		first:      first,
+		thereafter: thereafter,
+		interval:   interval,
+		clock:      SystemClock,
+		counters:   make(map[samplingKey]uint64),
+	}
+}
+
+// Clock sets the clock measuring the intervals of the sampler. Nil restores the SystemClock.
+func (s *CountSampler) Clock(c Clock) *CountSampler {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if c == nil {
+		c = SystemClock
+	}
+	s.clock = c
+	return s
+}
+
+func (s *CountSampler) Sample(l level.Level, template string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := s.clock.Now()
+	if !now.Before(s.reset) {
+		clear(s.counters)
+		s.reset = now.Add(s.interval)
+	}
+
+	key := samplingKey{level: l.Type(), template: template}
+	s.counters[key]++
+	n := s.counters[key]
+
+	if n <= s.first {
+		return true
+	}
+
+	return s.thereafter > 0 && (n-s.first)%s.thereafter == 0
+}
+
+// NewRandomSampler returns a sampler logging each message with the given probability, between 0 and 1.
+func NewRandomSampler(rate float64) Sampler {
+	return SamplerFunc(func(level.Level, string) bool {
+		return rand.Float64() < rate
+	})
+}
+
+// sampling applies a sampler to the messages of an Output and reports the dropped ones.
+type sampling struct {
+	sampler Sampler
+	report  time.Duration
+	output  *Output
+
+	lock sync.Mutex
+	//dropped counts the messages dropped since the last summary by level.
+	dropped map[string]uint64
+	timer   *time.Timer
+}
+
+func (s *sampling) sample(m *Message) bool {
+	//terminal levels are always delivered
+	if terminal(m.level) || s.sampler.Sample(m.level, m.template) {
+		return true
+	}
+
+	if s.report <= 0 {
+		return false
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.dropped[m.level.Type()]++
+	//the summary is scheduled by the first drop, so that an idle output has no pending timer
+	if s.timer == nil {
+		s.timer = time.AfterFunc(s.report, s.summarize)
+	}
+
+	return false
+}
+
+// summarize logs the number of messages dropped since the last summary with the warn level.
+func (s *sampling) summarize() {
+	s.lock.Lock()
+	dropped := s.dropped
+	s.dropped = make(map[string]uint64)
+	s.timer = nil
+	s.lock.Unlock()
+
+	levels := make([]string, 0, len(dropped))
+	var total uint64
+	for l, n := range dropped {
+		levels = append(levels, l)
+		total += n
+	}
+	sort.Strings(levels)
+
+	counts := make(map[any]any, len(dropped))
+	for _, l := range levels {
+		counts["dropped."+strings.ToLower(l)] = dropped[l]
+	}
+
+	m := newMessage(s.output, level.Warn())
+	m.Metadata(merge(s.output.metadata(), counts))
+	m.content = fmt.Appendf(m.content, "sampling dropped %d messages in the last %s", total, s.report)
+	//the summary is neither filtered nor sampled, it would otherwise be lost with the messages it reports
+	m.write()
+}
+
+// Sampler sets the sampler deciding which messages of the output are logged. Nil removes it.
+// Messages with the fatal and panic levels and messages logged within a transaction are never sampled.
+// When report is positive, the number of dropped messages by level is logged with the warn level
+// at most once per report interval.
+// Named loggers without a sampler use the sampler of their parent.
+func (o *Output) Sampler(s Sampler, report time.Duration) *Output {
+	if s == nil {
+		o.sampling = nil
+		return o
+	}
+
+	o.sampling = &sampling{
+		sampler: s,
+		report:  report,
+		output:  o,
+		dropped: make(map[string]uint64),
+	}
+	return o
+}
+
+// sampled reports whether the message passes the sampler of the output, inherited from the parent for named loggers.
+func (o *Output) sampled(m *Message) bool {
+	if o.sampling != nil {
+		return o.sampling.sample(m)
+	}
+
+	if o.parent != nil {
+		return o.parent.sampled(m)
+	}
+
+	return true
+}
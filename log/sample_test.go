@@ -0,0 +1,86 @@
+package log
+
+import (
+	"bytes"
+	"github.com/canghel3/telemetry/level"
+	"github.com/canghel3/telemetry/telemetrytest"
+	"gotest.tools/v3/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSampling(t *testing.T) {
+	t.Run("FIRST THEN EVERY", func(t *testing.T) {
+		var buffer bytes.Buffer
+		toBuffer := OutputDriver(&buffer).Sampler(NewCountSampler(time.Minute, 2, 3), 0)
+
+		for i := 1; i <= 10; i++ {
+			toBuffer.Info().Logf("request %d", i)
+		}
+		toBuffer.Error().Logf("request %d", 11)
+		toBuffer.Info().Log("other")
+
+		logged := buffer.String()
+		for _, i := range []string{"1", "2", "5", "8", "11"} {
+			assert.Assert(t, strings.Contains(logged, "request "+i+"\n"), i)
+		}
+		assert.Equal(t, strings.Count(logged, "request"), 5)
+		assert.Assert(t, strings.Contains(logged, "other\n"))
+	})
+
+	t.Run("INTERVAL", func(t *testing.T) {
+		s := NewCountSampler(20*time.Millisecond, 1, 0)
+		assert.Assert(t, s.Sample(level.Info(), "tick"))
+		assert.Assert(t, !s.Sample(level.Info(), "tick"))
+
+		time.Sleep(30 * time.Millisecond)
+		assert.Assert(t, s.Sample(level.Info(), "tick"))
+	})
+
+	t.Run("INTERVAL WITH CLOCK", func(t *testing.T) {
+		clock := telemetrytest.NewClock(time.Date(2024, 3, 2, 10, 0, 0, 0, time.UTC))
+		s := NewCountSampler(time.Minute, 1, 0).Clock(clock)
+		assert.Assert(t, s.Sample(level.Info(), "tick"))
+		assert.Assert(t, !s.Sample(level.Info(), "tick"))
+
+		clock.Advance(time.Minute)
+		assert.Assert(t, s.Sample(level.Info(), "tick"))
+	})
+
+	t.Run("RANDOM", func(t *testing.T) {
+		never, always := NewRandomSampler(0), NewRandomSampler(1)
+		for i := 0; i < 100; i++ {
+			assert.Assert(t, !never.Sample(level.Info(), "message"))
+			assert.Assert(t, always.Sample(level.Info(), "message"))
+		}
+	})
+
+	t.Run("NEVER SAMPLES FATAL", func(t *testing.T) {
+		defer func(exit func(int)) { ExitFunc = exit }(ExitFunc)
+		ExitFunc = func(int) {}
+
+		var buffer bytes.Buffer
+		OutputDriver(&buffer).Sampler(NewRandomSampler(0), 0).Fatal().Log("cannot continue")
+		assert.Assert(t, strings.Contains(buffer.String(), "FATAL cannot continue\n"))
+	})
+
+	t.Run("SUMMARY", func(t *testing.T) {
+		driver := newBufferedDriver(t)
+		toDriver := OutputDriver(driver).Sampler(NewCountSampler(time.Minute, 1, 0), 20*time.Millisecond)
+
+		for i := 0; i < 5; i++ {
+			toDriver.Info().Log("repeated")
+			toDriver.Debug().Log("repeated")
+		}
+
+		time.Sleep(100 * time.Millisecond)
+
+		driver.lock.Lock()
+		logged := driver.buffered.String()
+		driver.lock.Unlock()
+
+		assert.Equal(t, strings.Count(logged, "repeated"), 2)
+		assert.Assert(t, strings.Contains(logged, "WARN dropped.debug:4 dropped.info:4 sampling dropped 8 messages in the last 20ms\n"), logged)
+	})
+}
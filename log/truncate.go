@@ -0,0 +1,239 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/canghel3/telemetry/encoder"
+	"strconv"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+// Truncation bounds the size of the entries of an Output, so that one huge payload cannot produce a line
+// too large for the tools shipping the logs. Zero disables a bound.
+// Truncated text ends with a marker such as …[truncated 1234 bytes].
+type Truncation struct {
+	// MaxMessage is the maximum length of the content of an entry in bytes.
+	MaxMessage int
+	// MaxValue is the maximum length of a metadata value in bytes, values of other types than strings
+	// and byte slices being measured as formatted by fmt.Sprint.
+	MaxValue int
+	// MaxEntry is the maximum size of an encoded entry in bytes, including its line feed.
+	// Transaction blocks rendered by a TxEncoder are bounded to MaxEntry bytes per entry.
+	// It does not apply to drivers serializing entries themselves, see drivers.EntryWriter.
+	MaxEntry int
+}
+
+// truncating applies a Truncation to the entries of an Output and counts the truncated ones.
+type truncating struct {
+	limits    Truncation
+	truncated atomic.Uint64
+}
+
+// entry truncates the content and metadata values of the entry, reporting whether anything was truncated.
+// The content and metadata are replaced rather than modified, since they are shared with the message and output.
+func (t *truncating) entry(e *encoder.Entry) bool {
+	if t == nil {
+		return false
+	}
+
+	truncated := false
+	if t.limits.MaxMessage > 0 && len(e.Content) > t.limits.MaxMessage {
+		e.Content = truncate(nil, e.Content, t.limits.MaxMessage)
+		truncated = true
+	}
+
+	if t.limits.MaxValue > 0 {
+		var metadata map[any]any
+		for k, v := range e.Metadata {
+			value, ok := t.value(v)
+			if !ok {
+				continue
+			}
+
+			if metadata == nil {
+				metadata = make(map[any]any, len(e.Metadata))
+				for k, v := range e.Metadata {
+					metadata[k] = v
+				}
+			}
+
+			metadata[k] = value
+		}
+
+		if metadata != nil {
+			e.Metadata = metadata
+			truncated = true
+		}
+	}
+
+	return truncated
+}
+
+// value returns the truncated metadata value and whether it exceeded the limit.
+func (t *truncating) value(v any) (string, bool) {
+	switch value := v.(type) {
+	case string:
+		if len(value) <= t.limits.MaxValue {
+			return "", false
+		}
+
+		return string(truncate(nil, []byte(value), t.limits.MaxValue)), true
+	case []byte:
+		if len(value) <= t.limits.MaxValue {
+			return "", false
+		}
+
+		return string(truncate(nil, value, t.limits.MaxValue)), true
+	case int, int64, int32, uint, uint64, uint32, float64, float32, bool, nil:
+		//too short to ever exceed a sensible limit
+		return "", false
+	default:
+		s := fmt.Sprint(value)
+		if len(s) <= t.limits.MaxValue {
+			return "", false
+		}
+
+		return string(truncate(nil, []byte(s), t.limits.MaxValue)), true
+	}
+}
+
+// encoded truncates the entries encoded in the buffer from start to max bytes, keeping their final line feed,
+// and reports whether they were truncated.
+func (t *truncating) encoded(buf *bytes.Buffer, start, max int) bool {
+	if t == nil || max <= 0 || buf.Len()-start <= max {
+		return false
+	}
+
+	encoded := buf.Bytes()[start:]
+	//the marker replaces the end of the entry, the line feed ending it is kept
+	newline := bytes.HasSuffix(encoded, []byte("\n"))
+	if newline {
+		encoded, max = encoded[:len(encoded)-1], max-1
+	}
+
+	var limited []byte
+	if bytes.IndexByte(encoded, ansiEscape) >= 0 {
+		limited = truncateEscaped(encoded, max)
+	} else {
+		limited = truncate(nil, encoded, max)
+	}
+	buf.Truncate(start)
+	buf.Write(limited)
+	if newline {
+		buf.WriteByte('\n')
+	}
+	return true
+}
+
+// count counts a truncated entry.
+func (t *truncating) count(truncated bool) {
+	if t != nil && truncated {
+		t.truncated.Add(1)
+	}
+}
+
+// truncate appends to dst the text cut so that it fits in max bytes together with the marker telling how many bytes were cut.
+// The text is cut on a character boundary. When the marker alone exceeds max, the text is cut to max bytes without it.
+func truncate(dst, text []byte, max int) []byte {
+	marker := markerLen(len(text))
+	if marker > max {
+		return append(dst, text[:cut(text, max)]...)
+	}
+
+	keep := cut(text, max-marker)
+	dst = append(dst, text[:keep]...)
+	return appendMarker(dst, len(text)-keep)
+}
+
+// ansiEscape starts the escape sequences coloring console entries, ansiReset ends their colors.
+const (
+	ansiEscape = '\x1b'
+	ansiReset  = "\x1b[0m"
+)
+
+// truncateEscaped is truncate for text holding ANSI escape sequences, such as console entries.
+// The text is not cut within an escape sequence and its colors are reset before the marker, so that a truncated
+// entry does not color the lines following it.
+func truncateEscaped(text []byte, max int) []byte {
+	if max < len(ansiReset) {
+		return nil
+	}
+
+	room := max - len(ansiReset)
+	marked := markerLen(len(text)) <= room
+	if marked {
+		room -= markerLen(len(text))
+	}
+
+	keep := cut(text, room)
+	if i := bytes.LastIndexByte(text[:keep], ansiEscape); i >= 0 && bytes.IndexByte(text[i:keep], 'm') < 0 {
+		keep = i
+	}
+
+	dst := append(text[:keep:keep], ansiReset...)
+	if marked {
+		dst = appendMarker(dst, len(text)-keep)
+	}
+	return dst
+}
+
+// cut returns the length of at most n bytes the text can be cut to without cutting a character in half.
+func cut(text []byte, n int) int {
+	if n >= len(text) {
+		return len(text)
+	}
+
+	for n > 0 && !utf8.RuneStart(text[n]) {
+		n--
+	}
+	return n
+}
+
+// appendMarker appends the marker telling that n bytes were truncated.
+func appendMarker(dst []byte, n int) []byte {
+	dst = append(dst, "…[truncated "...)
+	dst = strconv.AppendInt(dst, int64(n), 10)
+	return append(dst, " bytes]"...)
+}
+
+// markerLen returns the length of the longest marker needed for a text of n bytes.
+func markerLen(n int) int {
+	return len("…[truncated ") + len(strconv.Itoa(n)) + len(" bytes]")
+}
+
+// Truncate sets the bounds of the size of the entries of the output. The zero Truncation removes them.
+// Named loggers without bounds use the bounds of their parent.
+func (o *Output) Truncate(t Truncation) *Output {
+	if t.MaxMessage <= 0 && t.MaxValue <= 0 && t.MaxEntry <= 0 {
+		o.truncating = nil
+		return o
+	}
+
+	o.truncating = &truncating{limits: t}
+	return o
+}
+
+// Truncated returns the number of entries truncated by the bounds of the output, inherited from the parent for named loggers,
+// since they were set.
+func (o *Output) Truncated() uint64 {
+	t := o.truncation()
+	if t == nil {
+		return 0
+	}
+
+	return t.truncated.Load()
+}
+
+// truncation returns the bounds of the output, inherited from the parent for named loggers. Nil when the entries are not bounded.
+func (o *Output) truncation() *truncating {
+	if o.truncating != nil {
+		return o.truncating
+	}
+
+	if o.parent != nil {
+		return o.parent.truncation()
+	}
+
+	return nil
+}
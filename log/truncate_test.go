@@ -0,0 +1,160 @@
+package log
+
+import (
+	"bytes"
+	"github.com/canghel3/telemetry/config"
+	"github.com/canghel3/telemetry/encoder"
+	"github.com/canghel3/telemetry/telemetrytest"
+	"gotest.tools/v3/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTruncation(t *testing.T) {
+	cfg := config.Default()
+	cfg.Formatting.LogConfig.Timestamp = time.RFC3339
+	cfg.Formatting.LogConfig.Timezone = "UTC"
+	cfg.Formatting.TxConfig = config.TxConfig(cfg.Formatting.LogConfig)
+	clock := telemetrytest.NewClock(time.Date(2024, 3, 2, 10, 0, 0, 0, time.UTC))
+
+	t.Run("MESSAGE", func(t *testing.T) {
+		var buffer bytes.Buffer
+		toBuffer := New(&buffer, cfg).Clock(clock).Truncate(Truncation{MaxMessage: 30})
+
+		toBuffer.Info().Logf("payload %s", strings.Repeat("x", 100))
+		toBuffer.Info().Log("short")
+
+		assert.Equal(t, buffer.String(), "2024-03-02T10:00:00Z INFO payloa…[truncated 102 bytes]\n2024-03-02T10:00:00Z INFO short\n")
+		assert.Equal(t, toBuffer.Truncated(), uint64(1))
+	})
+
+	t.Run("METADATA VALUE", func(t *testing.T) {
+		recorder := telemetrytest.NewRecorder()
+		metadata := map[any]any{"body": strings.Repeat("é", 20), "status": 200}
+		toRecorder := OutputDriver(recorder).Truncate(Truncation{MaxValue: 28}).Metadata(metadata)
+
+		toRecorder.Info().Log("request")
+
+		entries := recorder.Entries()
+		assert.Equal(t, len(entries), 1)
+		//characters are not cut in half
+		assert.Equal(t, entries[0].Metadata["body"], "éé…[truncated 36 bytes]")
+		assert.Equal(t, entries[0].Metadata["status"], 200)
+		assert.Equal(t, len(metadata["body"].(string)), 40, "the metadata of the output must not be modified")
+		assert.Equal(t, toRecorder.Truncated(), uint64(1))
+	})
+
+	t.Run("ENTRY", func(t *testing.T) {
+		var buffer bytes.Buffer
+		metadata := map[any]any{"a": strings.Repeat("a", 50), "b": strings.Repeat("b", 50)}
+		toBuffer := New(&buffer, cfg).Clock(clock).Truncate(Truncation{MaxEntry: 64}).Metadata(metadata)
+
+		toBuffer.Info().Log("message")
+
+		assert.Equal(t, buffer.Len(), 64)
+		assert.Equal(t, buffer.String(), "2024-03-02T10:00:00Z INFO a:aaaaaaaaaaa…[truncated 100 bytes]\n")
+	})
+
+	t.Run("TRANSACTION", func(t *testing.T) {
+		var buffer bytes.Buffer
+		toBuffer := New(&buffer, cfg).Clock(clock).Truncate(Truncation{MaxMessage: 30, MaxEntry: 200})
+
+		tx := BeginTx()
+		tx.Append(toBuffer.Info().Msg(strings.Repeat("y", 100)))
+		tx.Append(toBuffer.Info().Msg("short"))
+		tx.Log()
+
+		lines := strings.Split(strings.TrimSuffix(buffer.String(), "\n"), "\n")
+		assert.Equal(t, len(lines), 2)
+		assert.Assert(t, strings.HasSuffix(lines[0], "| INFO yyyyyy…[truncated 94 bytes]"), lines[0])
+		assert.Assert(t, strings.HasSuffix(lines[1], "| INFO short"), lines[1])
+		assert.Equal(t, toBuffer.Truncated(), uint64(1))
+	})
+
+	t.Run("TRANSACTION BLOCK", func(t *testing.T) {
+		var buffer bytes.Buffer
+		toBuffer := New(&buffer, cfg).Encoder(encoder.Console().Colors(false)).Truncate(Truncation{MaxEntry: 50})
+
+		tx := BeginTx()
+		tx.Append(toBuffer.Info().Msg(strings.Repeat("z", 100)))
+		tx.Append(toBuffer.Info().Msg("short"))
+		tx.Log()
+
+		assert.Equal(t, buffer.Len(), 100)
+		assert.Assert(t, strings.HasSuffix(buffer.String(), " bytes]\n"), buffer.String())
+		assert.Equal(t, toBuffer.Truncated(), uint64(1))
+	})
+
+	t.Run("INHERITED", func(t *testing.T) {
+		defer unregister("truncation", "truncation.child")
+
+		var buffer bytes.Buffer
+		parent := Named("truncation").Driver(&buffer).Truncate(Truncation{MaxMessage: 30})
+		child := Named("truncation.child")
+
+		child.Info().Log(strings.Repeat("x", 100))
+		assert.Equal(t, parent.Truncated(), uint64(1))
+		assert.Equal(t, child.Truncated(), uint64(1))
+	})
+}
+
+func TestTruncate(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		text     string
+		max      int
+		expected string
+	}{
+		{name: "MARKER", text: strings.Repeat("x", 30), max: 25, expected: "xx…[truncated 28 bytes]"},
+		{name: "CHARACTER BOUNDARY", text: "ab€cd", max: 24, expected: "ab…[truncated 5 bytes]"},
+		//the euro sign is not cut in half
+		{name: "CHARACTER NOT CUT IN HALF", text: "ab€cd", max: 25, expected: "ab…[truncated 5 bytes]"},
+		//the marker does not fit, the text is cut without it
+		{name: "MAX BELOW MARKER", text: strings.Repeat("x", 15), max: 10, expected: "xxxxxxxxxx"},
+		{name: "MAX BELOW MARKER ON CHARACTER BOUNDARY", text: "ab€cd€€€€", max: 4, expected: "ab"},
+		{name: "ZERO", text: "abc", max: 0, expected: ""},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			truncated := truncate(nil, []byte(test.text), test.max)
+			assert.Equal(t, string(truncated), test.expected)
+			assert.Assert(t, len(truncated) <= test.max)
+		})
+	}
+}
+
+func TestTruncateEscaped(t *testing.T) {
+	const red, dim = "\x1b[31m", "\x1b[2m"
+	text := dim + "10:11:12" + ansiReset + " " + red + "ERROR" + ansiReset + " " + strings.Repeat("x", 40)
+
+	for _, test := range []struct {
+		name     string
+		max      int
+		expected string
+	}{
+		{name: "AFTER ESCAPE", max: 50, expected: dim + "10:11:12" + ansiReset + " " + red + "E" + ansiReset + "…[truncated 49 bytes]"},
+		//cutting within the color of the level backs up to before it
+		{name: "WITHIN ESCAPE", max: 45, expected: dim + "10:11:12" + ansiReset + " " + ansiReset + "…[truncated 55 bytes]"},
+		//the marker does not fit, the colors are still reset
+		{name: "MAX BELOW MARKER", max: 12, expected: dim + "10:1" + ansiReset},
+		{name: "MAX BELOW RESET", max: 3, expected: ""},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			truncated := truncateEscaped([]byte(text), test.max)
+			assert.Equal(t, string(truncated), test.expected)
+			assert.Assert(t, len(truncated) <= test.max)
+		})
+	}
+
+	t.Run("CONSOLE ENTRY", func(t *testing.T) {
+		var buffer bytes.Buffer
+		toBuffer := OutputDriver(&buffer).Encoder(encoder.Console().Colors(true)).Truncate(Truncation{MaxEntry: 40})
+
+		toBuffer.Error().Log(strings.Repeat("x", 100))
+		assert.Assert(t, buffer.Len() <= 40, buffer.String())
+		assert.Assert(t, strings.Contains(buffer.String(), ansiReset+"…[truncated "), "%q", buffer.String())
+		assert.Assert(t, strings.HasSuffix(buffer.String(), " bytes]\n"), "%q", buffer.String())
+		//every escape sequence is complete, the content has no m to be mistaken for the end of one
+		assert.Equal(t, strings.Count(buffer.String(), "\x1b["), strings.Count(buffer.String(), "m"), "%q", buffer.String())
+	})
+}
@@ -2,17 +2,29 @@ package log
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"github.com/canghel3/telemetry/config"
+	"github.com/canghel3/telemetry/drivers"
+	"github.com/canghel3/telemetry/encoder"
 	"github.com/google/uuid"
+	"io"
 	"os"
-	"time"
+	"sync"
 )
 
 type Tx struct {
+	//the lock allows a fatal or panic log on another goroutine to flush the transaction.
+	lock sync.Mutex
+
 	messages []*Message
 	id       string
 	commited bool
 	metadata map[any]any
+
+	processors []Processor
+	hooks      []Hook
+	clock      Clock
 }
 
 func BeginTx() *Tx {
@@ -33,62 +45,193 @@ func BeginTxWithMetadata(metadata map[any]any) *Tx {
 	}
 }
 
+// Append adds the message to the transaction.
 func (tx *Tx) Append(message *Message) {
+	tx.lock.Lock()
+	defer tx.lock.Unlock()
+
 	if !tx.commited {
 		tx.messages = append(tx.messages, message)
 	}
 }
 
+// FlushOnExit makes the transaction pending until it is logged or discarded, so that Flush, and therefore
+// fatal and panic messages, log it. Transactions that did not opt in are left alone and released once unreachable.
+func (tx *Tx) FlushOnExit() *Tx {
+	tx.lock.Lock()
+	defer tx.lock.Unlock()
+
+	if !tx.commited {
+		pending.Store(tx, struct{}{})
+	}
+
+	return tx
+}
+
+// Discard drops the appended messages without logging them.
+func (tx *Tx) Discard() {
+	tx.lock.Lock()
+	defer tx.lock.Unlock()
+
+	tx.commited = true
+	tx.messages = nil
+	pending.Delete(tx)
+}
+
 // Log send the existing message entries to their respective output driver.
+// Entries below the minimum level of their output are dropped,
+// as are entries exceeding the limits of their output when the limits apply to transactions.
+// Entries of an output whose encoder renders transactions as a block are written together,
+// at the position of the first entry of that output.
 // Any error is written to os.Stderr
 func (tx *Tx) Log() {
+	tx.lock.Lock()
+	defer tx.lock.Unlock()
+
 	if !tx.commited {
 		tx.commited = true
+		pending.Delete(tx)
+
+		var messages []*Message
 		for _, msg := range tx.messages {
-			//TODO: enable/disable formatting based on config
-			formattedOutput := tx.formatTransactionOutput(msg)
-			_, err := msg.output.driver.Write(formattedOutput)
-			if err != nil {
-				//write the error encountered during logging to os.Stderr. wip: any configured file
-				//we could write to the log output driver because it implements the required w io.Writer,
-				//but if the output driver is fatally broken, we also lose the error messages.
-				fmt.Fprintf(os.Stderr, "failed to write log %s: %s\n", formattedOutput, err.Error())
+			if !msg.output.enabled(msg.level) {
+				continue
+			}
+
+			msg.format(msg.args)
+			if msg.output.allowed(msg, tx) {
+				messages = append(messages, msg)
+			}
+		}
+
+		blocks := make(map[*Output][]*Message)
+		for _, msg := range messages {
+			if _, ok := msg.output.enc().(encoder.TxEncoder); ok {
+				blocks[msg.output] = append(blocks[msg.output], msg)
+			}
+		}
+
+		for _, msg := range messages {
+			block, ok := blocks[msg.output]
+			if !ok {
+				tx.write(msg.output, []*Message{msg})
+				continue
+			}
+
+			if len(block) > 0 {
+				tx.write(msg.output, block)
+				//mark the block as written
+				blocks[msg.output] = nil
 			}
 		}
 	}
 }
 
-// TODO: complete formatter
-func (tx *Tx) formatTransactionOutput(msg *Message) []byte {
-	var buffer bytes.Buffer
+// write encodes the messages and sends them to the output driver in a single write.
+func (tx *Tx) write(output *Output, messages []*Message) {
+	//load the configuration once so a concurrent reload cannot mix settings within one block
+	compiled := output.compiled()
+	cfg := config.LogConfig(compiled.cfg.Formatting.TxConfig)
+
+	truncating := output.truncation()
+	entries := make([]*encoder.Entry, 0, len(messages))
+	//truncated tells which entries were truncated, by index in entries
+	truncated := make([]bool, 0, len(messages))
+	for _, msg := range messages {
+		e := tx.entry(msg)
+		compiled.enrich(e)
+		if !output.process(e) || !processAll(tx.processors, e) {
+			continue
+		}
 
-	// format timestamp
-	timestampFormat := "2006-01-02 15:04:05"
-	if len(msg.output.config.Formatting.LogConfig.Timestamp) > 0 {
-		timestampFormat = msg.output.config.Formatting.LogConfig.Timestamp
+		compiled.redactor.Redact(e)
+		truncated = append(truncated, truncating.entry(e))
+		entries = append(entries, e)
 	}
 
-	//transaction log prefix
-	buffer.WriteString(time.Now().Format(timestampFormat))
-	buffer.WriteByte(' ')
-	buffer.WriteString("TRANSACTION " + tx.id + " | ")
+	if len(entries) == 0 {
+		return
+	}
 
-	// format level
-	buffer.WriteString(msg.level.Type())
-	buffer.WriteByte(' ')
+	err := writeEntries(output.writer(), entries, cfg, func(buffer *bytes.Buffer) {
+		var maxEntry int
+		if truncating != nil {
+			maxEntry = truncating.limits.MaxEntry
+		}
 
-	// format metadata
-	for k, v := range tx.metadata {
-		fmt.Fprintf(&buffer, "%v:%v ", k, v)
+		if txEncoder, ok := output.enc().(encoder.TxEncoder); ok && !cfg.FormattingDisabled {
+			txEncoder.EncodeTx(buffer, tx.id, entries, cfg)
+			//the block cannot be split into entries, it is bounded as a whole and counted as one truncated entry
+			if truncating.encoded(buffer, 0, maxEntry*len(entries)) {
+				truncated[len(truncated)-1] = true
+			}
+			return
+		}
+
+		for i, e := range entries {
+			start := buffer.Len()
+			if cfg.FormattingDisabled {
+				buffer.Write(e.Content)
+			} else {
+				output.enc().Encode(buffer, e, cfg)
+			}
+
+			if truncating.encoded(buffer, start, maxEntry) {
+				truncated[i] = true
+			}
+		}
+	})
+	for _, t := range truncated {
+		truncating.count(t)
+	}
+	if err != nil {
+		//write the error encountered during logging to os.Stderr. wip: any configured file
+		//we could write to the log output driver because it implements the required w io.Writer,
+		//but if the output driver is fatally broken, we also lose the error messages.
+		fmt.Fprintf(os.Stderr, "failed to write transaction %s: %s\n", tx.id, err.Error())
 	}
-	//
-	//if len(tx.metadata) > 0 {
-	//	buffer.WriteByte(' ')
-	//}
 
-	// add content
-	buffer.Write(msg.content)
-	buffer.WriteByte('\n')
+	for _, e := range entries {
+		output.afterWrite(e, err)
+		hookAll(tx.hooks, e, err)
+	}
+}
 
-	return buffer.Bytes()
+// writeEntries hands the entries to drivers serializing them, together with the configuration for those rendering them,
+// or writes the bytes rendered by encode in a single write.
+func writeEntries(driver io.Writer, entries []*encoder.Entry, cfg config.LogConfig, encode func(buffer *bytes.Buffer)) error {
+	if formatted, ok := driver.(drivers.FormattedEntryWriter); ok {
+		var errs []error
+		for _, e := range entries {
+			errs = append(errs, formatted.WriteFormattedEntry(e, cfg))
+		}
+
+		return errors.Join(errs...)
+	}
+
+	if entryWriter, ok := driver.(drivers.EntryWriter); ok {
+		var errs []error
+		for _, e := range entries {
+			errs = append(errs, entryWriter.WriteEntry(e))
+		}
+
+		return errors.Join(errs...)
+	}
+
+	buffer := getBuffer()
+	defer putBuffer(buffer)
+
+	encode(buffer)
+	_, err := driver.Write(buffer.Bytes())
+	return err
+}
+
+// entry returns the encoder representation of the message carrying the transaction id and metadata.
+func (tx *Tx) entry(msg *Message) *encoder.Entry {
+	e := msg.entry(tx.id)
+	e.Metadata = encoder.ResolveMetadata(tx.metadata)
+	if tx.clock != nil {
+		e.Time = tx.clock.Now()
+	}
+	return e
 }
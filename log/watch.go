@@ -0,0 +1,169 @@
+package log
+
+import (
+	"fmt"
+	"github.com/canghel3/telemetry/config"
+	"github.com/fsnotify/fsnotify"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// reloadDelay groups the burst of events editors produce when saving a file into a single reload.
+const reloadDelay = 100 * time.Millisecond
+
+// Watcher reloads a configuration file whenever it changes on disk
+// and swaps the new configuration into every watched Output.
+type Watcher struct {
+	lock sync.Mutex
+
+	file    string
+	outputs []*Output
+	//loggers is set by WatchLoggers to reconfigure the named loggers on every change.
+	loggers bool
+	watcher *fsnotify.Watcher
+	timer   *time.Timer
+	done    chan struct{}
+	//closed is set by Close, reloads are not started once it is set.
+	closed bool
+	//reloads tracks the running reloads, so that Close can wait for them.
+	reloads sync.WaitGroup
+}
+
+// Watch starts watching the given configuration file and applies it to the outputs every time it changes.
+// A configuration that fails to load is reported to os.Stderr and the outputs keep their previous configuration.
+// Named loggers are left alone, see WatchLoggers.
+func Watch(file string, outputs ...*Output) (*Watcher, error) {
+	return watch(file, false, outputs)
+}
+
+// WatchLoggers is like Watch, and also applies the loggers section of the file to the named loggers with Configure
+// every time it changes. The named loggers are shared by the whole process, a single watcher should reconfigure them.
+func WatchLoggers(file string, outputs ...*Output) (*Watcher, error) {
+	return watch(file, true, outputs)
+}
+
+func watch(file string, loggers bool, outputs []*Output) (*Watcher, error) {
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create watcher: %w", err)
+	}
+
+	//watch the directory instead of the file, editors usually replace the file on save
+	//which would silently drop a watch placed on the file itself.
+	file = filepath.Clean(file)
+	err = fw.Add(filepath.Dir(file))
+	if err != nil {
+		fw.Close()
+		return nil, fmt.Errorf("failed to watch %s: %w", file, err)
+	}
+
+	w := &Watcher{
+		file:    file,
+		outputs: outputs,
+		loggers: loggers,
+		watcher: fw,
+		done:    make(chan struct{}),
+	}
+
+	go w.run()
+	return w, nil
+}
+
+// Add starts applying the watched configuration to the given outputs.
+// The outputs receive the configuration on the next change of the file.
+func (w *Watcher) Add(outputs ...*Output) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.outputs = append(w.outputs, outputs...)
+}
+
+// Close stops watching the configuration file and waits for a running reload to finish.
+// The outputs keep the last applied configuration.
+func (w *Watcher) Close() error {
+	w.lock.Lock()
+	w.closed = true
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	w.lock.Unlock()
+
+	err := w.watcher.Close()
+	<-w.done
+	w.reloads.Wait()
+	return err
+}
+
+func (w *Watcher) run() {
+	defer close(w.done)
+
+	for {
+		select {
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+
+			if filepath.Clean(event.Name) != w.file || !event.Has(fsnotify.Write|fsnotify.Create) {
+				continue
+			}
+
+			w.lock.Lock()
+			if w.timer == nil {
+				w.timer = time.AfterFunc(reloadDelay, w.reload)
+			} else {
+				w.timer.Reset(reloadDelay)
+			}
+			w.lock.Unlock()
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+
+			fmt.Fprintf(os.Stderr, "failed to watch config %s: %s\n", w.file, err.Error())
+		}
+	}
+}
+
+func (w *Watcher) reload() {
+	//the timer may fire while Close stops it, the closed flag keeps it from reloading afterwards.
+	w.lock.Lock()
+	if w.closed {
+		w.lock.Unlock()
+		return
+	}
+	w.reloads.Add(1)
+	w.lock.Unlock()
+	defer w.reloads.Done()
+
+	cfg, err := loadConfig(w.file)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to reload config: %s\n", err.Error())
+		return
+	}
+
+	if w.loggers {
+		Configure(*cfg)
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	for _, o := range w.outputs {
+		//every output gets its own copy so that a later Settings call on one of them cannot leak into the others.
+		c := cfg.Clone()
+		o.swap(&c)
+	}
+}
+
+// loadConfig reads the file into a new configuration the way config.LoadFileEnv does, on top of the default configuration
+// and overridden by the environment, so that the precedence of the environment holds across reloads.
+// Unknown keys and invalid values are rejected so that a typo never replaces a working configuration.
+func loadConfig(file string) (*config.PkgConfig, error) {
+	cfg, err := config.LoadFileEnv(file, os.Environ())
+	if err != nil {
+		return nil, err
+	}
+
+	return &cfg, nil
+}
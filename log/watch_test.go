@@ -0,0 +1,164 @@
+package log
+
+import (
+	"github.com/canghel3/telemetry/config"
+	"github.com/canghel3/telemetry/level"
+	"gotest.tools/v3/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	writeConfig := func(t *testing.T, name, timestamp string) {
+		content := `{"formatting": {"log": {"timestamp": "` + timestamp + `"}}}`
+		err := os.WriteFile(name, []byte(content), 0600)
+		assert.NilError(t, err)
+	}
+
+	waitForTimestamp := func(o *Output, timestamp string) bool {
+		deadline := time.Now().Add(2 * time.Second)
+		for time.Now().Before(deadline) {
+			if o.conf().Formatting.LogConfig.Timestamp == timestamp {
+				return true
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		return false
+	}
+
+	t.Run("RELOADS ON CHANGE", func(t *testing.T) {
+		name := filepath.Join(t.TempDir(), "telemetry.json")
+		writeConfig(t, name, "2006")
+
+		o1 := Stdout()
+		o2 := Stdout()
+		w, err := Watch(name, o1)
+		assert.NilError(t, err)
+		defer w.Close()
+		w.Add(o2)
+
+		writeConfig(t, name, "15:04")
+		assert.Assert(t, waitForTimestamp(o1, "15:04"))
+		assert.Assert(t, waitForTimestamp(o2, "15:04"))
+	})
+
+	t.Run("DEFAULTS AND ENVIRONMENT", func(t *testing.T) {
+		t.Setenv("TELEMETRY_FORMATTING_LOG_TIMEZONE", "UTC")
+
+		name := filepath.Join(t.TempDir(), "telemetry.json")
+		writeConfig(t, name, "2006")
+
+		o := Stdout()
+		w, err := Watch(name, o)
+		assert.NilError(t, err)
+		defer w.Close()
+
+		writeConfig(t, name, "15:04")
+		assert.Assert(t, waitForTimestamp(o, "15:04"))
+
+		//the reloaded file is read on top of the defaults and overridden by the environment
+		cfg := o.conf()
+		assert.Equal(t, cfg.Formatting.LogConfig.Timezone, "UTC")
+		assert.Equal(t, cfg.Formatting.TxConfig.Timestamp, config.Default().Formatting.TxConfig.Timestamp)
+		assert.DeepEqual(t, cfg.Formatting.LogConfig.FieldOrder, config.Default().Formatting.LogConfig.FieldOrder)
+	})
+
+	t.Run("KEEPS OLD CONFIG ON ERROR", func(t *testing.T) {
+		name := filepath.Join(t.TempDir(), "telemetry.json")
+		writeConfig(t, name, "2006")
+
+		o := Stdout()
+		w, err := Watch(name, o)
+		assert.NilError(t, err)
+		defer w.Close()
+
+		writeConfig(t, name, "15:04")
+		assert.Assert(t, waitForTimestamp(o, "15:04"))
+
+		err = os.WriteFile(name, []byte(`{"formatting": {"log": {"timestamp": `), 0600)
+		assert.NilError(t, err)
+		time.Sleep(5 * reloadDelay)
+		assert.Equal(t, o.conf().Formatting.LogConfig.Timestamp, "15:04")
+
+		err = os.WriteFile(name, []byte(`{"formatting": {"log": {"timestmp": "2006"}}}`), 0600)
+		assert.NilError(t, err)
+		time.Sleep(5 * reloadDelay)
+		assert.Equal(t, o.conf().Formatting.LogConfig.Timestamp, "15:04")
+
+		writeConfig(t, name, "2006-01")
+		assert.Assert(t, waitForTimestamp(o, "2006-01"))
+	})
+
+	t.Run("NAMED LOGGERS", func(t *testing.T) {
+		defer unregister("watched")
+		defer Configure(config.PkgConfig{})
+
+		writeLoggers := func(name, lvl string) {
+			err := os.WriteFile(name, []byte(`{"loggers": {"watched": {"level": "`+lvl+`"}}}`), 0600)
+			assert.NilError(t, err)
+		}
+
+		hasLevel := func(lvl level.Level) bool {
+			minimum, _ := Named("watched").MinLevel()
+			return minimum != nil && minimum.Type() == lvl.Type()
+		}
+
+		waitForLevel := func(lvl level.Level) bool {
+			deadline := time.Now().Add(2 * time.Second)
+			for time.Now().Before(deadline) {
+				if hasLevel(lvl) {
+					return true
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+			return false
+		}
+
+		//Watch leaves the named loggers alone
+		name := filepath.Join(t.TempDir(), "telemetry.json")
+		writeLoggers(name, "debug")
+		w, err := Watch(name)
+		assert.NilError(t, err)
+		writeLoggers(name, "error")
+		time.Sleep(5 * reloadDelay)
+		assert.NilError(t, w.Close())
+		assert.Assert(t, !hasLevel(level.Error()))
+
+		w, err = WatchLoggers(name)
+		assert.NilError(t, err)
+		defer w.Close()
+		writeLoggers(name, "warn")
+		assert.Assert(t, waitForLevel(level.Warn()))
+	})
+
+	t.Run("STOPS AFTER CLOSE", func(t *testing.T) {
+		name := filepath.Join(t.TempDir(), "telemetry.json")
+		writeConfig(t, name, "2006")
+
+		o := Stdout()
+		w, err := Watch(name, o)
+		assert.NilError(t, err)
+		assert.NilError(t, w.Close())
+
+		writeConfig(t, name, "15:04")
+		time.Sleep(5 * reloadDelay)
+		assert.Assert(t, o.conf().Formatting.LogConfig.Timestamp != "15:04")
+	})
+
+	t.Run("NO RELOAD AFTER CLOSE", func(t *testing.T) {
+		name := filepath.Join(t.TempDir(), "telemetry.json")
+		writeConfig(t, name, "2006")
+
+		o := Stdout()
+		w, err := Watch(name, o)
+		assert.NilError(t, err)
+		assert.NilError(t, w.Close())
+
+		//a timer firing while Close stops it must not apply the configuration
+		writeConfig(t, name, "15:04")
+		w.reload()
+		assert.Assert(t, o.conf().Formatting.LogConfig.Timestamp != "15:04")
+	})
+}
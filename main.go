@@ -3,7 +3,6 @@ package main
 import (
 	"github.com/canghel3/telemetry/config"
 	"github.com/canghel3/telemetry/log"
-	"github.com/spf13/viper"
 	"os"
 	"time"
 )
@@ -19,27 +18,23 @@ func (cd *CustomDriver) Write(p []byte) error {
 	return nil
 }
 
-func init() {
-	v := viper.New()
-	v.AutomaticEnv()
-	v.SetEnvPrefix("TELEMETRY")
-	v.AddConfigPath("./")
-	v.SetConfigName("telemetry")
-	v.SetConfigType("json")
-	err := v.ReadInConfig()
+// loadConfig makes telemetry.json, overridden by the TELEMETRY_ environment variables,
+// the configuration of every output created without an explicit configuration.
+func loadConfig() {
+	cfg, err := config.LoadFileEnv("./telemetry.json", os.Environ())
 	if err != nil {
-		log.Stdout().Error().Logf("failed to read config: %s", err.Error())
+		log.Stdout().Error().Logf("failed to load config: %s", err.Error())
+		return
 	}
 
-	err = v.Unmarshal(&config.PkgConfiguration)
-	if err != nil {
-		log.Stdout().Error().Logf("failed to unmarshal config: %s", err.Error())
-	}
+	config.PkgConfiguration = cfg
 }
 
 // formatting is very basic, but not vital for proper functionality
 // solve TODOs
 func main() {
+	loadConfig()
+
 	const logfile = "./telemetry.log"
 	const configFile = "./config.json"
 	log.Stdout().Error().Log("HELLO")
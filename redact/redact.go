@@ -0,0 +1,243 @@
+// Package redact removes sensitive data from entries before they are encoded.
+package redact
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/canghel3/telemetry/config"
+	"github.com/canghel3/telemetry/encoder"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Mask replaces redacted values in the mask mode.
+const Mask = "[REDACTED]"
+
+// patterns are the built-in patterns listed by config.RedactionPatterns.
+var patterns = map[string]*pattern{
+	"credit_card":  {expr: regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`), valid: luhn},
+	"bearer_token": {expr: regexp.MustCompile(`(?i)\bbearer\s+([A-Za-z0-9\-._~+/]+=*)`)},
+	"email":        {expr: regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)},
+}
+
+type pattern struct {
+	expr *regexp.Regexp
+	//valid filters out matches that are not sensitive, e.g. digit sequences failing the card checksum.
+	valid func(s string) bool
+}
+
+// Redactor removes the sensitive data declared in a config.RedactionConfig from entries.
+// It is safe for concurrent use.
+type Redactor struct {
+	keys     []string
+	patterns []*pattern
+	mode     string
+}
+
+// New returns a Redactor for the configuration.
+// Invalid keys, patterns and expressions are skipped and reported in the returned error,
+// the Redactor applying the valid ones.
+func New(cfg config.RedactionConfig) (*Redactor, error) {
+	r := &Redactor{mode: cfg.Mode}
+	if len(r.mode) == 0 {
+		r.mode = "mask"
+	}
+
+	var errs []error
+	for _, key := range cfg.Keys {
+		_, err := path.Match(key, "")
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid key %q: %w", key, err))
+			continue
+		}
+
+		r.keys = append(r.keys, strings.ToLower(key))
+	}
+
+	for _, name := range cfg.Patterns {
+		p, ok := patterns[name]
+		if !ok {
+			errs = append(errs, fmt.Errorf("unknown pattern %q", name))
+			continue
+		}
+
+		r.patterns = append(r.patterns, p)
+	}
+
+	for _, expression := range cfg.Expressions {
+		expr, err := regexp.Compile(expression)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid expression %q: %w", expression, err))
+			continue
+		}
+
+		r.patterns = append(r.patterns, &pattern{expr: expr})
+	}
+
+	switch r.mode {
+	case "mask", "hash", "drop":
+	default:
+		errs = append(errs, fmt.Errorf("unknown mode %q", r.mode))
+		r.mode = "mask"
+	}
+
+	return r, errors.Join(errs...)
+}
+
+// Empty reports whether the Redactor has nothing to redact.
+func (r *Redactor) Empty() bool {
+	return r == nil || (len(r.keys) == 0 && len(r.patterns) == 0)
+}
+
+// Redact removes the sensitive data from the content, metadata and error of the entry.
+// The metadata map of the entry is replaced rather than modified, since it is shared with the output.
+func (r *Redactor) Redact(e *encoder.Entry) {
+	if r.Empty() {
+		return
+	}
+
+	e.Content = r.content(e.Content)
+	e.Metadata = r.metadata(e.Metadata)
+
+	if e.Error != nil {
+		e.Error.Message = string(r.content([]byte(e.Error.Message)))
+		for i := range e.Error.Causes {
+			e.Error.Causes[i].Message = string(r.content([]byte(e.Error.Causes[i].Message)))
+		}
+	}
+}
+
+func (r *Redactor) metadata(metadata map[any]any) map[any]any {
+	var redacted map[any]any
+	for k, v := range metadata {
+		value, drop, changed := r.value(k, v)
+		if !changed {
+			continue
+		}
+
+		if redacted == nil {
+			redacted = make(map[any]any, len(metadata))
+			for k, v := range metadata {
+				redacted[k] = v
+			}
+		}
+
+		if drop {
+			delete(redacted, k)
+		} else {
+			redacted[k] = value
+		}
+	}
+
+	if redacted == nil {
+		return metadata
+	}
+
+	return redacted
+}
+
+// value returns the redacted metadata value, whether the key must be dropped and whether anything was redacted.
+func (r *Redactor) value(k, v any) (any, bool, bool) {
+	if r.sensitive(fmt.Sprint(k)) {
+		if r.mode == "drop" {
+			return nil, true, true
+		}
+
+		return r.replace(fmt.Sprint(v)), false, true
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		return v, false, false
+	}
+
+	redacted := string(r.content([]byte(s)))
+	return redacted, false, redacted != s
+}
+
+// sensitive reports whether the metadata key matches one of the configured keys.
+func (r *Redactor) sensitive(key string) bool {
+	key = strings.ToLower(key)
+	for _, k := range r.keys {
+		if ok, _ := path.Match(k, key); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// content redacts every match of the patterns. When a pattern has a capturing group,
+// only the text matched by the first group is redacted.
+func (r *Redactor) content(content []byte) []byte {
+	for _, p := range r.patterns {
+		matches := p.expr.FindAllSubmatchIndex(content, -1)
+		if len(matches) == 0 {
+			continue
+		}
+
+		var redacted []byte
+		last := 0
+		for _, match := range matches {
+			start, end := match[0], match[1]
+			if len(match) > 2 && match[2] >= 0 {
+				start, end = match[2], match[3]
+			}
+
+			if p.valid != nil && !p.valid(string(content[start:end])) {
+				continue
+			}
+
+			redacted = append(redacted, content[last:start]...)
+			redacted = append(redacted, r.replace(string(content[start:end]))...)
+			last = end
+		}
+
+		if redacted != nil {
+			content = append(redacted, content[last:]...)
+		}
+	}
+
+	return content
+}
+
+// replace returns the replacement of the sensitive value according to the mode.
+func (r *Redactor) replace(s string) string {
+	switch r.mode {
+	case "hash":
+		//a hash keeps redacted values comparable across entries without revealing them
+		sum := sha256.Sum256([]byte(s))
+		return "sha256:" + hex.EncodeToString(sum[:8])
+	case "drop":
+		return ""
+	default:
+		return Mask
+	}
+}
+
+// luhn reports whether the digits of s pass the Luhn checksum used by payment card numbers.
+func luhn(s string) bool {
+	var sum, n int
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+
+		d := int(c - '0')
+		if n%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+
+		sum += d
+		n++
+	}
+
+	return n > 0 && sum%10 == 0
+}
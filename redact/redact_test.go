@@ -0,0 +1,87 @@
+package redact
+
+import (
+	"errors"
+	"fmt"
+	"github.com/canghel3/telemetry/config"
+	"github.com/canghel3/telemetry/encoder"
+	"gotest.tools/v3/assert"
+	"strings"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	cfg := config.RedactionConfig{
+		Keys:        []string{"password", "*_token"},
+		Patterns:    []string{"credit_card", "bearer_token", "email"},
+		Expressions: []string{`ssn=(\d{3}-\d{2}-\d{4})`},
+	}
+
+	t.Run("MASK", func(t *testing.T) {
+		r, err := New(cfg)
+		assert.NilError(t, err)
+
+		metadata := map[any]any{"password": "hunter2", "API_TOKEN": 42, "user": "jane@example.com", "order": "4111111111111112"}
+		e := &encoder.Entry{
+			Content:  []byte("paid with 4111 1111 1111 1111 using Bearer abc.def-ghi ssn=123-45-6789 order 1234567890123"),
+			Metadata: metadata,
+			Error:    encoder.NewError(fmt.Errorf("login: %w", errors.New("unknown user jane@example.com"))),
+		}
+		r.Redact(e)
+
+		assert.Equal(t, string(e.Content), "paid with [REDACTED] using Bearer [REDACTED] ssn=[REDACTED] order 1234567890123")
+		assert.DeepEqual(t, e.Metadata, map[any]any{"password": Mask, "API_TOKEN": Mask, "user": Mask, "order": "4111111111111112"})
+		assert.Equal(t, e.Error.Message, "login: unknown user [REDACTED]")
+		assert.Equal(t, e.Error.Causes[0].Message, "unknown user [REDACTED]")
+		//the metadata of the output is left untouched
+		assert.Equal(t, metadata["password"], "hunter2")
+	})
+
+	t.Run("HASH", func(t *testing.T) {
+		hashed := cfg
+		hashed.Mode = "hash"
+		r, err := New(hashed)
+		assert.NilError(t, err)
+
+		first := &encoder.Entry{Content: []byte("sent to jane@example.com")}
+		second := &encoder.Entry{Content: []byte("jane@example.com replied")}
+		r.Redact(first)
+		r.Redact(second)
+
+		//the same value always hashes the same
+		hash := strings.TrimPrefix(string(first.Content), "sent to ")
+		assert.Assert(t, strings.HasPrefix(hash, "sha256:"))
+		assert.Equal(t, string(second.Content), hash+" replied")
+	})
+
+	t.Run("DROP", func(t *testing.T) {
+		dropped := cfg
+		dropped.Mode = "drop"
+		r, err := New(dropped)
+		assert.NilError(t, err)
+
+		e := &encoder.Entry{Content: []byte("reset for jane@example.com"), Metadata: map[any]any{"password": "hunter2", "user": "jane"}}
+		r.Redact(e)
+
+		assert.Equal(t, string(e.Content), "reset for ")
+		assert.DeepEqual(t, e.Metadata, map[any]any{"user": "jane"})
+	})
+
+	t.Run("INVALID", func(t *testing.T) {
+		r, err := New(config.RedactionConfig{Keys: []string{"[", "secret"}, Patterns: []string{"iban"}, Expressions: []string{"("}})
+		assert.ErrorContains(t, err, `invalid key "["`)
+		assert.ErrorContains(t, err, `unknown pattern "iban"`)
+		assert.ErrorContains(t, err, `invalid expression "("`)
+
+		e := &encoder.Entry{Metadata: map[any]any{"secret": "value"}}
+		r.Redact(e)
+		assert.DeepEqual(t, e.Metadata, map[any]any{"secret": Mask})
+	})
+
+	t.Run("BUILT-IN PATTERNS", func(t *testing.T) {
+		for _, name := range config.RedactionPatterns {
+			_, ok := patterns[name]
+			assert.Assert(t, ok, name)
+		}
+	})
+}
@@ -0,0 +1,76 @@
+package telemetrytest
+
+import (
+	"fmt"
+	"github.com/canghel3/telemetry/level"
+	"strings"
+	"testing"
+)
+
+// AssertLogged fails the test unless an entry with the level and containing the content was recorded.
+func AssertLogged(t testing.TB, r *Recorder, l level.Level, content string) {
+	t.Helper()
+
+	for _, e := range r.Entries() {
+		if e.Level != nil && e.Level.Type() == l.Type() && strings.Contains(e.Content, content) {
+			return
+		}
+	}
+
+	t.Errorf("no %s entry containing %q was logged, recorded:\n%s", l.Type(), content, r.String())
+}
+
+// AssertNotLogged fails the test if an entry containing the content was recorded, whatever its level.
+func AssertNotLogged(t testing.TB, r *Recorder, content string) {
+	t.Helper()
+
+	for _, e := range r.Entries() {
+		if strings.Contains(e.Content, content) {
+			t.Errorf("an entry containing %q was logged, recorded:\n%s", content, r.String())
+			return
+		}
+	}
+}
+
+// AssertNoErrors fails the test if an entry with the error severity or above, or with an attached error, was recorded.
+func AssertNoErrors(t testing.TB, r *Recorder) {
+	t.Helper()
+
+	for _, e := range r.Entries() {
+		if e.Error != nil || (e.Level != nil && level.Severity(e.Level) >= level.SeverityError) {
+			t.Errorf("an error was logged, recorded:\n%s", r.String())
+			return
+		}
+	}
+}
+
+// AssertCount fails the test unless exactly n entries with the level were recorded.
+func AssertCount(t testing.TB, r *Recorder, l level.Level, n int) {
+	t.Helper()
+
+	if count := r.Count(l); count != n {
+		t.Errorf("expected %d %s entries, got %d, recorded:\n%s", n, l.Type(), count, r.String())
+	}
+}
+
+// String lists the recorded entries, one per line.
+func (r *Recorder) String() string {
+	var sb strings.Builder
+	for _, e := range r.Entries() {
+		levelType := ""
+		if e.Level != nil {
+			levelType = e.Level.Type()
+		}
+
+		fmt.Fprintf(&sb, "\t%s %s", levelType, e.Content)
+		if len(e.TxID) > 0 {
+			fmt.Fprintf(&sb, " (transaction %s)", e.TxID)
+		}
+		if e.Error != nil {
+			fmt.Fprintf(&sb, " error=%q", e.Error.Message)
+		}
+		sb.WriteByte('\n')
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,104 @@
+// Package telemetrytest provides a recording driver, a fake clock and assertions for testing code that logs.
+package telemetrytest
+
+import (
+	"github.com/canghel3/telemetry/encoder"
+	"github.com/canghel3/telemetry/level"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Entry is a recorded log entry.
+type Entry struct {
+	Time     time.Time
+	Level    level.Level
+	Content  string
+	Metadata map[any]any
+	// TxID is the id of the transaction the entry was logged in. Empty for entries logged outside a transaction.
+	TxID  string
+	Error *encoder.Error
+}
+
+// Recorder is a driver recording the entries logged to it instead of writing them anywhere.
+// It is safe for concurrent use.
+//
+//	recorder := telemetrytest.NewRecorder()
+//	logger := log.OutputDriver(recorder)
+type Recorder struct {
+	lock    sync.Mutex
+	entries []Entry
+}
+
+// NewRecorder returns an empty Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// WriteEntry records the entry. Outputs hand their entries to the Recorder through this method rather than Write.
+func (r *Recorder) WriteEntry(e *encoder.Entry) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	metadata := make(map[any]any, len(e.Metadata))
+	for k, v := range e.Metadata {
+		metadata[k] = v
+	}
+
+	r.entries = append(r.entries, Entry{
+		Time:     e.Time,
+		Level:    e.Level,
+		Content:  string(e.Content),
+		Metadata: metadata,
+		TxID:     e.TxID,
+		Error:    e.Error,
+	})
+	return nil
+}
+
+// Write records every line written by anything unaware of entries as an entry holding only the content.
+func (r *Recorder) Write(p []byte) (int, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for _, line := range strings.Split(strings.TrimSuffix(string(p), "\n"), "\n") {
+		r.entries = append(r.entries, Entry{Time: time.Now(), Content: line})
+	}
+
+	return len(p), nil
+}
+
+// Entries returns the recorded entries in the order they were logged.
+func (r *Recorder) Entries() []Entry {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	entries := make([]Entry, len(r.entries))
+	copy(entries, r.entries)
+	return entries
+}
+
+// Count returns the number of recorded entries with the level.
+func (r *Recorder) Count(l level.Level) int {
+	return r.CountByLevel()[l.Type()]
+}
+
+// CountByLevel returns the number of recorded entries by level name.
+func (r *Recorder) CountByLevel() map[string]int {
+	counts := make(map[string]int)
+	for _, e := range r.Entries() {
+		if e.Level != nil {
+			counts[e.Level.Type()]++
+		}
+	}
+
+	return counts
+}
+
+// Reset drops every recorded entry.
+func (r *Recorder) Reset() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.entries = nil
+}
@@ -0,0 +1,100 @@
+package telemetrytest
+
+import (
+	"errors"
+	"github.com/canghel3/telemetry/level"
+	"github.com/canghel3/telemetry/log"
+	"gotest.tools/v3/assert"
+	"testing"
+	"time"
+)
+
+// failures records the failures reported by the assertions instead of failing the test.
+type failures struct {
+	testing.TB
+	msgs []string
+}
+
+func (f *failures) Helper() {}
+
+func (f *failures) Errorf(format string, args ...any) {
+	f.msgs = append(f.msgs, format)
+}
+
+func TestRecorder(t *testing.T) {
+	t.Run("ENTRIES", func(t *testing.T) {
+		recorder := NewRecorder()
+		logger := log.OutputDriver(recorder).Metadata(map[any]any{"service": "payments"})
+
+		logger.Info().Logf("charged %d", 42)
+		tx := log.BeginTxWithMetadata(map[any]any{"order": 7})
+		tx.Append(logger.Warn().Msg("retrying"))
+		tx.Append(logger.Error().Err(errors.New("declined")).Msg("failed"))
+		tx.Log()
+
+		entries := recorder.Entries()
+		assert.Equal(t, len(entries), 3)
+		assert.Equal(t, entries[0].Content, "charged 42")
+		assert.Equal(t, entries[0].Level.Type(), "INFO")
+		assert.DeepEqual(t, entries[0].Metadata, map[any]any{"service": "payments"})
+		assert.Equal(t, entries[0].TxID, "")
+		assert.Assert(t, time.Since(entries[0].Time) < time.Minute)
+
+		assert.Assert(t, len(entries[1].TxID) > 0)
+		assert.Equal(t, entries[1].TxID, entries[2].TxID)
+		assert.DeepEqual(t, entries[2].Metadata, map[any]any{"order": 7})
+		assert.Equal(t, entries[2].Error.Message, "declined")
+
+		assert.Equal(t, recorder.Count(level.Warn()), 1)
+		assert.DeepEqual(t, recorder.CountByLevel(), map[string]int{"INFO": 1, "WARN": 1, "ERROR": 1})
+
+		recorder.Reset()
+		assert.Equal(t, len(recorder.Entries()), 0)
+	})
+
+	t.Run("ASSERTIONS", func(t *testing.T) {
+		recorder := NewRecorder()
+		logger := log.OutputDriver(recorder)
+		logger.Info().Log("user signed in")
+
+		AssertLogged(t, recorder, level.Info(), "signed in")
+		AssertNotLogged(t, recorder, "signed out")
+		AssertCount(t, recorder, level.Info(), 1)
+		AssertNoErrors(t, recorder)
+
+		f := &failures{TB: t}
+		AssertLogged(f, recorder, level.Warn(), "signed in")
+		AssertNotLogged(f, recorder, "signed in")
+		AssertCount(f, recorder, level.Info(), 2)
+		assert.Equal(t, len(f.msgs), 3)
+
+		f = &failures{TB: t}
+		logger.Warn().Err(errors.New("expired")).Log("session")
+		AssertNoErrors(f, recorder)
+		logger.Error().Log("failed")
+		AssertNoErrors(f, recorder)
+		assert.Equal(t, len(f.msgs), 2)
+	})
+
+	t.Run("WRITE", func(t *testing.T) {
+		recorder := NewRecorder()
+		_, err := recorder.Write([]byte("first\nsecond\n"))
+		assert.NilError(t, err)
+
+		entries := recorder.Entries()
+		assert.Equal(t, len(entries), 2)
+		assert.Equal(t, entries[1].Content, "second")
+	})
+}
+
+func TestClock(t *testing.T) {
+	start := time.Date(2024, 3, 2, 10, 0, 0, 0, time.UTC)
+	clock := NewClock(start)
+	assert.Equal(t, clock.Now(), start)
+
+	clock.Advance(time.Minute)
+	assert.Equal(t, clock.Now(), start.Add(time.Minute))
+
+	clock.Set(start)
+	assert.Equal(t, clock.Now(), start)
+}